@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bjaka-max/dispeys/pkg/actions"
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+)
+
+// newActionRegistry добавляет к общим действиям те, что меняют состояние пульта
+func (d *deck) newActionRegistry() *actions.Registry {
+	registry := actions.NewRegistry()
+	registry.Register(actions.SwitchProfile, func(ctx context.Context, action *actions.Action) error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if action.Profile == "" {
+			d.stopProcessDetect = false
+		} else {
+			d.stopProcessDetect = true
+			d.settingsNP = appdetector.GetSettingsForProcess(action.Profile)
+		}
+		d.resetPages()
+		d.show()
+		return nil
+	})
+	registry.Register(actions.Page, func(ctx context.Context, action *actions.Action) error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.navigate(action.Page)
+		return nil
+	})
+	registry.Register(actions.FocusOrRun, func(ctx context.Context, action *actions.Action) error {
+		return appdetector.FocusOrRun(ctx, action.Program)
+	})
+	return registry
+}
+
+// runAction выполняет действие кнопки index и ждёт его завершения,
+// поэтому вызывается без d.mu. С feedback кнопка подсвечивается
+// по результату.
+func (d *deck) runAction(action actions.Action, index int, button *appdetector.Button) {
+	if action.IsEmpty() {
+		return
+	}
+	fmt.Printf("action: %v\n", action)
+	ctx := actions.WithEnvironment(context.Background(), d.environment(index))
+	err := d.actions.Run(ctx, &action)
+	if err != nil {
+		fmt.Println("Ошибка выполнения действия:", err)
+	}
+	if action.Feedback && button != nil {
+		d.flash(index, button, err == nil)
+	}
+}
+
+// environment собирает окружение команд: env и cwd текущего профиля
+// и переменные DISPEYS_* с кнопкой, профилем и пультом. Переменные
+// активного окна считаются, только если действие запускает программу.
+func (d *deck) environment(index int) actions.Environment {
+	d.mu.Lock()
+	var env actions.Environment
+	profile := ""
+	if app := d.current(); app != nil {
+		env = app.Environment()
+		profile = app.Key()
+	}
+	d.mu.Unlock()
+
+	env.Vars = append(env.Vars,
+		"DISPEYS_KEY_INDEX="+strconv.Itoa(index),
+		"DISPEYS_PROFILE="+profile,
+		"DISPEYS_DEVICE_SERIAL="+d.serial,
+	)
+	env.Lazy = windowEnvironment
+	return env
+}
+
+// windowEnvironment — переменные активного окна. ID и PID берутся из кеша
+// детектора, заголовок читается заново.
+func windowEnvironment() []string {
+	window := appdetector.ActiveWindow()
+	return []string{
+		"DISPEYS_ACTIVE_WINDOW=" + window.ID,
+		"DISPEYS_ACTIVE_PID=" + window.PID,
+		"DISPEYS_WINDOW_TITLE=" + appdetector.WindowTitle(window.ID),
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bjaka-max/dispeys/cmd/controller/config"
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	"github.com/bjaka-max/dispeys/pkg/icons"
+)
+
+// Пульт получает не больше одного кадра за этот интервал, сколько бы
+// анимаций ни было на экране: загрузка кадра занимает HID, и нажатия
+// кнопок не должны её ждать
+const animationFrameInterval = 200 * time.Millisecond
+
+// frameLimiter раздаёт анимациям пульта очередь на загрузку кадров
+type frameLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait ждёт своей очереди; false, если анимацию остановили раньше
+func (l *frameLimiter) wait(stop chan struct{}) bool {
+	l.mu.Lock()
+	at := l.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(animationFrameInterval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// playAnimation по кругу показывает кадры анимации кнопки
+func (d *deck) playAnimation(stop chan struct{}, index int, button *appdetector.Button) {
+	frames, err := animationFrames(button.Animation)
+	if err != nil {
+		fmt.Println("Ошибка анимации:", err)
+		return
+	}
+	for i := 0; ; i = (i + 1) % len(frames) {
+		if !d.frameLimit.wait(stop) {
+			return
+		}
+		d.setFrame(stop, index, button, frames[i].Path)
+		if len(frames) == 1 {
+			return
+		}
+		timer := time.NewTimer(frames[i].Delay)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// setFrame показывает кадр вместо иконки кнопки
+func (d *deck) setFrame(stop chan struct{}, index int, button *appdetector.Button, frame string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	d.frames[button] = frame
+	if d.dev != nil {
+		updateButton(d.dev, index, *button, d.view(button))
+	}
+}
+
+func animationFrames(animation *appdetector.Animation) ([]icons.Frame, error) {
+	delay := icons.DefaultFrameDelay
+	if animation.DelayMs > 0 {
+		delay = time.Duration(animation.DelayMs) * time.Millisecond
+	}
+	if animation.Gif != "" {
+		path := animation.Gif
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(config.GetIconsDir(), path)
+		}
+		frames, err := icons.ExtractGIF(path, config.GetGeneratedIconsDir())
+		if err != nil {
+			return nil, err
+		}
+		if animation.DelayMs > 0 {
+			frames = append([]icons.Frame(nil), frames...)
+			for i := range frames {
+				frames[i].Delay = delay
+			}
+		}
+		return frames, nil
+	}
+	if len(animation.Frames) == 0 {
+		return nil, fmt.Errorf("в анимации нет кадров")
+	}
+	frames := make([]icons.Frame, 0, len(animation.Frames))
+	for _, frame := range animation.Frames {
+		frameDelay := delay
+		if frame.DelayMs > 0 {
+			frameDelay = time.Duration(frame.DelayMs) * time.Millisecond
+		}
+		frames = append(frames, icons.Frame{Path: frame.Icon, Delay: frameDelay})
+	}
+	return frames, nil
+}
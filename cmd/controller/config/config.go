@@ -6,11 +6,17 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+
+	"github.com/bjaka-max/dispeys/pkg/xdg"
 )
 
 const AppVersion = "0.0.1"
 const AppName = "dispeysController"
 
+// Переменная окружения с серийным номером эмулятора пульта.
+// Если задана, вместо реального HID используется эмулятор.
+const EmulatorEnv = "DISPEYS_EMULATOR"
+
 func GetHomeDir() string {
 	usr, err := user.Current()
 	if err != nil {
@@ -31,6 +37,19 @@ func GetTempDir() string {
 	return filepath.Join(os.TempDir(), AppName)
 }
 
+// GetGeneratedIconsDir — кэш иконок, нарисованных по icon_spec
+func GetGeneratedIconsDir() string {
+	return filepath.Join(GetTempDir(), "icons")
+}
+
+func GetEmulatorSerial() string {
+	return os.Getenv(EmulatorEnv)
+}
+
+func GetEmulatorSnapshotPath() string {
+	return filepath.Join(GetTempDir(), "emulator.png")
+}
+
 func GetEditorForTextFile() (string) {
 	desktopOut, err := exec.Command("xdg-mime", "query", "default", "text/plain").Output()
 	if err != nil {
@@ -39,26 +58,15 @@ func GetEditorForTextFile() (string) {
 	desktopFile := strings.TrimSpace(string(desktopOut))
 
 	// 3. Ищем в системных путях Exec=
-	paths := []string{
-		GetHomeDir() + "/.local/share/applications/" + desktopFile,
-		"/usr/local/share/applications/" + desktopFile,
-		"/usr/share/applications/" + desktopFile,
+	path := xdg.FindDesktopFile(desktopFile)
+	if path == "" {
+		return ""
 	}
-
-	for _, p := range paths {
-		text, err := os.ReadFile(p)
-		if err != nil {
-			continue
-		}
-		lines := strings.Split(string(text), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "Exec=") {
-				return strings.TrimSpace(strings.TrimPrefix(line, "Exec="))
-			}
-		}
+	entry, err := xdg.ReadDesktopEntry(path)
+	if err != nil {
+		return ""
 	}
-
-	return ""
+	return entry["Exec"]
 }
 
 func ShellEscape(s string) string {
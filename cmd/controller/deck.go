@@ -0,0 +1,434 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/actions"
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	"github.com/bjaka-max/dispeys/pkg/gestures"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+)
+
+// deck — состояние одного пульта. Живёт дольше самого устройства,
+// поэтому после переподключения пульт возвращается к тому же профилю.
+type deck struct {
+	serial   string
+	detector *gestures.Detector
+	actions  *actions.Registry
+
+	mu                sync.Mutex
+	dev               *ulanzid200.UlanziD200Device
+	process           string
+	settings          *appdetector.Application
+	settingsNP        *appdetector.Application
+	stopProcessDetect bool
+	// Текущая страница или папка и история переходов для #back
+	loc     location
+	history []location
+	// Текущие состояния кнопок-переключателей
+	states map[*appdetector.Button]int
+	// Вычисленные подписи кнопок с шаблонами
+	texts map[*appdetector.Button]string
+	// Текущие кадры анимированных кнопок и очередь их загрузки
+	frames     map[*appdetector.Button]string
+	frameLimit frameLimiter
+	// Иконки, которыми кнопка подсвечивается после выполнения действия
+	flashes map[*appdetector.Button]string
+	// Закрывается, чтобы остановить обновления кнопок текущего экрана
+	updatesStop chan struct{}
+}
+
+// location — страница профиля и открытая на ней папка
+type location struct {
+	page   int
+	folder *appdetector.Button
+}
+
+// В папке последняя кнопка пульта (перед маленьким окном) возвращает назад
+const folderBackIndex = ulanzid200.SmallWindowIndex - 1
+
+var folderBackButton = appdetector.Button{
+	Name:    "Back",
+	Icon:    "gnome-application-exit.png",
+	Command: actions.Action{Type: actions.Page, Page: "back"},
+}
+
+func newDeck(serial string) *deck {
+	d := &deck{
+		serial:   serial,
+		detector: gestures.NewDetector(gestures.KeyConfig{}),
+		states:   make(map[*appdetector.Button]int),
+		texts:    make(map[*appdetector.Button]string),
+		frames:   make(map[*appdetector.Button]string),
+		flashes:  make(map[*appdetector.Button]string),
+	}
+	d.actions = d.newActionRegistry()
+	go d.handleGestures()
+	return d
+}
+
+func (d *deck) attach(dev *ulanzid200.UlanziD200Device) {
+	d.mu.Lock()
+	d.dev = dev
+	d.mu.Unlock()
+	go d.listen(dev)
+}
+
+func (d *deck) detach(dev *ulanzid200.UlanziD200Device) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dev == dev {
+		d.dev = nil
+		d.stopUpdates()
+	}
+}
+
+func (d *deck) listen(dev *ulanzid200.UlanziD200Device) {
+	refreshChan := dev.RefreshChan()
+	keyEventChan := dev.KeyEventChan()
+	for {
+		select {
+		case <-refreshChan:
+			d.mu.Lock()
+			d.show()
+			d.mu.Unlock()
+		case keyEvent := <-keyEventChan:
+			d.detector.Feed(keyEvent.Index, keyEvent.Pressed, keyEvent.Time)
+		case <-dev.Done():
+			return
+		}
+	}
+}
+
+func (d *deck) handleGestures() {
+	for event := range d.detector.Events() {
+		d.handleGesture(event)
+	}
+}
+
+// setProcess вызывается при смене активного окна
+func (d *deck) setProcess(process string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.process = process
+	settings := appdetector.GetSettingsForDevice(d.serial, process)
+	if !d.stopProcessDetect && settings != d.settings {
+		d.resetPages()
+	}
+	d.settings = settings
+	if !d.stopProcessDetect {
+		d.show()
+	}
+}
+
+func (d *deck) current() *appdetector.Application {
+	if d.stopProcessDetect {
+		return d.settingsNP
+	}
+	return d.settings
+}
+
+// buttons возвращает кнопки текущей страницы или открытой папки
+func (d *deck) buttons() []appdetector.Button {
+	app := d.current()
+	if app == nil {
+		return nil
+	}
+	if d.loc.folder != nil {
+		return folderButtons(d.loc.folder)
+	}
+	pages := app.GetPages()
+	if d.loc.page < 0 || d.loc.page >= len(pages) {
+		d.loc.page = 0
+	}
+	return pages[d.loc.page].Buttons
+}
+
+// buttonAt возвращает кнопку из настроек, а не её копию, чтобы по ней
+// можно было хранить состояние переключателя
+func (d *deck) buttonAt(index int) *appdetector.Button {
+	if d.loc.folder != nil {
+		if index == folderBackIndex {
+			return &folderBackButton
+		}
+		if index >= 0 && index < folderBackIndex && index < len(d.loc.folder.Buttons) {
+			return &d.loc.folder.Buttons[index]
+		}
+		return nil
+	}
+	buttons := d.buttons()
+	if index >= 0 && index < len(buttons) {
+		return &buttons[index]
+	}
+	return nil
+}
+
+// folderButtons раскладывает кнопки папки и ставит «Назад» на folderBackIndex.
+// Лишние кнопки папки не показываются.
+func folderButtons(folder *appdetector.Button) []appdetector.Button {
+	buttons := make([]appdetector.Button, folderBackIndex+1)
+	copy(buttons[:folderBackIndex], folder.Buttons)
+	buttons[folderBackIndex] = folderBackButton
+	return buttons
+}
+
+func (d *deck) resetPages() {
+	d.loc = location{}
+	d.history = nil
+}
+
+// openFolder показывает кнопки папки, предыдущий экран запоминается для #back
+func (d *deck) openFolder(folder *appdetector.Button) {
+	d.history = append(d.history, d.loc)
+	d.loc.folder = folder
+	fmt.Printf("Папка [%s]: %s\n", d.serial, folder.Name)
+	d.show()
+}
+
+func (d *deck) show() {
+	app := d.current()
+	if app == nil {
+		return
+	}
+	buttons := d.buttons()
+	d.detector.ResetKeyConfigs()
+	chords := make([]gestures.Chord, 0, len(app.Chords))
+	for _, chord := range app.Chords {
+		chords = append(chords, gestures.Chord{Keys: chord.Keys})
+	}
+	d.detector.SetChords(chords)
+	sequences := make([]gestures.Sequence, 0, len(app.Sequences))
+	for _, sequence := range app.Sequences {
+		sequences = append(sequences, gestures.Sequence{
+			Keys:   sequence.Keys,
+			Within: time.Duration(sequence.WithinMs) * time.Millisecond,
+		})
+	}
+	d.detector.SetSequences(sequences)
+	for i := range buttons {
+		longPress, doublePress, repeat := buttons[i].GestureTimings()
+		d.detector.SetKeyConfig(i, gestures.KeyConfig{
+			LongPress:   longPress,
+			DoublePress: doublePress,
+			Repeat:      repeat,
+		})
+	}
+	if d.dev != nil {
+		pages := len(app.GetPages())
+		if d.loc.folder != nil {
+			pages = 1
+		}
+		views := make([]buttonView, len(buttons))
+		for i := range buttons {
+			if button := d.buttonAt(i); button != nil {
+				views[i] = d.view(button)
+			}
+		}
+		setSettings(d.dev, buttons, views, d.loc.page, pages)
+		d.startUpdates()
+	}
+}
+
+func (d *deck) view(button *appdetector.Button) buttonView {
+	icon := d.flashes[button]
+	if icon == "" {
+		icon = d.frames[button]
+	}
+	return buttonView{state: d.states[button], text: d.texts[button], icon: icon}
+}
+
+// startUpdates запускает проверки состояния, пересчёт подписей и анимации для
+// видимых кнопок. Обновления предыдущего экрана останавливаются.
+func (d *deck) startUpdates() {
+	d.stopUpdates()
+	stop := make(chan struct{})
+	d.updatesStop = stop
+	for i := 0; i <= folderBackIndex; i++ {
+		button := d.buttonAt(i)
+		if button == nil {
+			continue
+		}
+		if button.IsToggle() && button.Status != nil {
+			go d.pollStatus(stop, i, button)
+		}
+		if button.Text != "" {
+			go d.pollText(stop, i, button)
+		}
+		if button.Animation != nil {
+			go d.playAnimation(stop, i, button)
+		}
+	}
+}
+
+func (d *deck) stopUpdates() {
+	if d.updatesStop != nil {
+		close(d.updatesStop)
+		d.updatesStop = nil
+	}
+}
+
+func (d *deck) handleGesture(event *gestures.Event) {
+	if action, button := d.gestureAction(event); !action.IsEmpty() {
+		go d.runAction(action, event.Index, button)
+	}
+}
+
+// gestureAction выбирает действие для жеста и кнопку, которой оно назначено
+// (nil для аккордов и последовательностей). Папки и переключатели
+// обрабатываются сразу, а действие выполняется уже без блокировки пульта:
+// макрос или долгая команда не должны задерживать следующие нажатия.
+func (d *deck) gestureAction(event *gestures.Event) (actions.Action, *appdetector.Button) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fmt.Printf("gesture [%s]: %d %v\n", d.serial, event.Index, event.Gesture)
+	app := d.current()
+	if app == nil {
+		return actions.Action{}, nil
+	}
+	switch event.Gesture {
+	case gestures.ChordPress:
+		if event.Binding < len(app.Chords) {
+			return app.Chords[event.Binding].Command, nil
+		}
+	case gestures.SequencePress:
+		if event.Binding < len(app.Sequences) {
+			return app.Sequences[event.Binding].Command, nil
+		}
+	default:
+		button := d.buttonAt(event.Index)
+		if button == nil {
+			return actions.Action{}, nil
+		}
+		if event.Gesture == gestures.ShortPress && button.IsFolder() {
+			d.openFolder(button)
+			return actions.Action{}, nil
+		}
+		if event.Gesture == gestures.ShortPress && button.IsToggle() {
+			return d.toggle(event.Index, button), button
+		}
+		return button.GestureCommand(event.Gesture.String()), button
+	}
+	return actions.Action{}, nil
+}
+
+// toggle показывает следующее состояние переключателя и возвращает
+// действие текущего
+func (d *deck) toggle(index int, button *appdetector.Button) actions.Action {
+	state := d.states[button]
+	command := button.GetState(state).Command
+	state = (state + 1) % len(button.States)
+	d.states[button] = state
+	if d.dev != nil {
+		updateButton(d.dev, index, *button, d.view(button))
+	}
+	return command
+}
+
+func (d *deck) navigate(target string) {
+	app := d.current()
+	if app == nil {
+		return
+	}
+	count := len(app.GetPages())
+	page := d.loc.page
+	switch target {
+	case "back":
+		if len(d.history) == 0 {
+			return
+		}
+		d.loc = d.history[len(d.history)-1]
+		d.history = d.history[:len(d.history)-1]
+		d.show()
+		return
+	case "home":
+		d.resetPages()
+		d.show()
+		return
+	case "next":
+		page = (d.loc.page + 1) % count
+	case "prev":
+		page = (d.loc.page - 1 + count) % count
+	default:
+		page = app.FindPage(target)
+		if page < 0 {
+			fmt.Printf("Страница %q не найдена\n", target)
+			return
+		}
+	}
+	// Переход на другую страницу закрывает папку
+	if page == d.loc.page && d.loc.folder == nil {
+		return
+	}
+	d.history = append(d.history, d.loc)
+	d.loc = location{page: page}
+	fmt.Printf("Страница [%s]: %d/%d\n", d.serial, page+1, count)
+	d.show()
+}
+
+// deckRegistry хранит пульты по серийному номеру
+type deckRegistry struct {
+	mu      sync.Mutex
+	decks   map[string]*deck
+	process string
+}
+
+func newDeckRegistry() *deckRegistry {
+	return &deckRegistry{decks: make(map[string]*deck)}
+}
+
+func (r *deckRegistry) attach(serial string, dev *ulanzid200.UlanziD200Device) {
+	r.mu.Lock()
+	d, ok := r.decks[serial]
+	if !ok {
+		d = newDeck(serial)
+		r.decks[serial] = d
+	}
+	process := r.process
+	r.mu.Unlock()
+
+	d.attach(dev)
+	d.setProcess(process)
+}
+
+func (r *deckRegistry) detach(serial string, dev *ulanzid200.UlanziD200Device) {
+	r.mu.Lock()
+	d, ok := r.decks[serial]
+	r.mu.Unlock()
+	if ok {
+		d.detach(dev)
+	}
+}
+
+func (r *deckRegistry) setProcess(process string) {
+	r.mu.Lock()
+	r.process = process
+	decks := make([]*deck, 0, len(r.decks))
+	for _, d := range r.decks {
+		decks = append(decks, d)
+	}
+	r.mu.Unlock()
+
+	for _, d := range decks {
+		d.setProcess(process)
+	}
+}
+
+// run раздаёт пультам смену активного окна и события подключения
+func (r *deckRegistry) run(processChangedChan <-chan string, deviceEventChan <-chan *ulanzid200.DeviceEvent) {
+	for {
+		select {
+		case process := <-processChangedChan:
+			r.setProcess(process)
+		case event := <-deviceEventChan:
+			switch event.Type {
+			case ulanzid200.DeviceAdded:
+				r.attach(event.Serial, event.Device)
+			case ulanzid200.DeviceRemoved:
+				r.detach(event.Serial, event.Device)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/emulator"
+)
+
+const testSettings = `{
+  "default": {
+    "buttons": [
+      { "icon": "red.png", "text": "Copy" },
+      { "icon": "blue.png", "text": "Paste" }
+    ]
+  },
+  "editor": {
+    "pages": [
+      { "name": "main", "buttons": [
+        { "icon": "red.png", "text": "Build" },
+        { "icon": "blue.png", "command": { "type": "page", "page": "next" } }
+      ] },
+      { "name": "debug", "buttons": [
+        { "icon": "blue.png", "text": "Step" },
+        { "icon": "red.png", "command": { "type": "page", "page": "prev" } }
+      ] }
+    ]
+  }
+}`
+
+// testController — контроллер, собранный как в onReady, но поверх эмулятора
+type testController struct {
+	emu     *emulator.Emulator
+	manager *ulanzid200.DeviceManager
+	process chan string
+}
+
+func startTestController(t *testing.T, serial string) *testController {
+	t.Helper()
+	dir := t.TempDir()
+	iconsDir := filepath.Join(dir, "icons")
+	if err := os.MkdirAll(iconsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIcon(t, filepath.Join(iconsDir, "red.png"), color.RGBA{0xff, 0, 0, 0xff})
+	writeTestIcon(t, filepath.Join(iconsDir, "blue.png"), color.RGBA{0, 0, 0xff, 0xff})
+	settingsPath := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(settingsPath, []byte(testSettings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := appdetector.AppSettings
+	t.Cleanup(func() { appdetector.AppSettings = saved })
+	appdetector.AppSettings = appdetector.Settings{}
+	if _, err := appdetector.LoadAppSettings(settingsPath, iconsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testController{emu: emulator.New(serial), process: make(chan string)}
+	c.manager = ulanzid200.NewDeviceManager(c.emu, ulanzid200.CLOCK, iconsDir, filepath.Join(dir, "tmp"))
+	c.manager.SetHotplugSource(c.emu.Hotplug())
+	go newDeckRegistry().run(c.process, c.manager.DeviceEventChan())
+	c.manager.Start()
+	t.Cleanup(c.manager.Stop)
+	return c
+}
+
+func writeTestIcon(t *testing.T, path string, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// labels возвращает подписи кнопок, показанные на эмуляторе
+func labels(state emulator.State, count int) []string {
+	result := make([]string, count)
+	for i := range result {
+		if view := state.Buttons[i].CurrentView(); view != nil {
+			result[i] = view.Text
+		}
+	}
+	return result
+}
+
+// waitLabels ждёт, пока на эмуляторе не окажутся подписи want, и проверяет,
+// что у кнопок загружены иконки
+func (c *testController) waitLabels(t *testing.T, want ...string) emulator.State {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		state := c.emu.State()
+		got := labels(state, len(want))
+		if equalStrings(got, want) {
+			for i := range want {
+				if view := state.Buttons[i].CurrentView(); len(view.IconData) == 0 {
+					t.Errorf("кнопка %d без иконки", i)
+				}
+			}
+			return state
+		}
+		select {
+		case <-c.emu.Changed():
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("подписи %q, ожидалось %q", got, want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestControllerUploadsPages(t *testing.T) {
+	c := startTestController(t, "E2E0001")
+
+	state := c.waitLabels(t, "Copy", "Paste")
+	if state.FullUploads == 0 {
+		t.Error("страница не загружалась целиком")
+	}
+	if state.Brightness != 100 {
+		t.Errorf("яркость %d", state.Brightness)
+	}
+
+	c.process <- "editor"
+	c.waitLabels(t, "Build", "1/2")
+
+	// Кнопка навигации переключает страницу
+	if err := c.emu.Click(1); err != nil {
+		t.Fatal(err)
+	}
+	c.waitLabels(t, "Step", "2/2")
+
+	if errs := c.emu.Errors(); len(errs) > 0 {
+		t.Fatalf("нарушения протокола: %v", errs)
+	}
+}
+
+func TestControllerRestoresPageAfterReplug(t *testing.T) {
+	c := startTestController(t, "E2E0002")
+	c.waitLabels(t, "Copy", "Paste")
+	c.process <- "editor"
+	c.waitLabels(t, "Build", "1/2")
+	if err := c.emu.Click(1); err != nil {
+		t.Fatal(err)
+	}
+	c.waitLabels(t, "Step", "2/2")
+
+	c.emu.Unplug()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.manager.Devices()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("пульт не отключён")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.emu.Plug()
+
+	// Пульт возвращается к той же странице профиля и получает её целиком
+	state := c.waitLabels(t, "Step", "2/2")
+	if state.FullUploads == 0 {
+		t.Error("после переподключения страница не загружена целиком")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	"github.com/bjaka-max/dispeys/pkg/icons"
+)
+
+// Сколько держится подсветка результата действия
+const feedbackDuration = 700 * time.Millisecond
+
+// Цвета подсветки: действие выполнено или завершилось ошибкой
+const (
+	feedbackSuccess = "#2e7d32"
+	feedbackFailure = "#c62828"
+)
+
+// flash ненадолго показывает иконку кнопки на зелёном или красном фоне
+func (d *deck) flash(index int, button *appdetector.Button, success bool) {
+	d.mu.Lock()
+	icon, err := feedbackIcon(button.GetState(d.states[button]), success)
+	if err != nil {
+		d.mu.Unlock()
+		fmt.Println("Ошибка подсветки кнопки:", err)
+		return
+	}
+	d.flashes[button] = icon
+	d.refreshButton(index, button)
+	d.mu.Unlock()
+
+	time.Sleep(feedbackDuration)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Пока шла подсветка, кнопку могли подсветить снова
+	if d.flashes[button] == icon {
+		delete(d.flashes, button)
+		d.refreshButton(index, button)
+	}
+}
+
+// refreshButton заново показывает кнопку, если она всё ещё на экране
+func (d *deck) refreshButton(index int, button *appdetector.Button) {
+	if d.dev != nil && d.buttonAt(index) == button {
+		updateButton(d.dev, index, *button, d.view(button))
+	}
+}
+
+// feedbackIcon рисует иконку состояния уменьшенной на цветном фоне
+func feedbackIcon(state appdetector.ButtonState, success bool) (string, error) {
+	spec := icons.Spec{Image: state.Icon, ImageScale: 0.7}
+	if state.IconSpec != nil {
+		spec = *state.IconSpec
+		spec.Gradient = nil
+	}
+	spec.Background = feedbackFailure
+	if success {
+		spec.Background = feedbackSuccess
+	}
+	path, err := iconGenerator.Generate(spec)
+	if err != nil && spec.Image != "" {
+		// Картинку не удалось прочитать: хватит и цветного фона
+		spec.Image = ""
+		return iconGenerator.Generate(spec)
+	}
+	return path, err
+}
@@ -3,20 +3,27 @@ package main
 import (
 	_ "embed"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/getlantern/systray"
 
 	"github.com/bjaka-max/dispeys/cmd/controller/config"
+	"github.com/bjaka-max/dispeys/pkg/actions"
 	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
 	"github.com/bjaka-max/dispeys/pkg/autostart"
+	"github.com/bjaka-max/dispeys/pkg/icons"
 	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/emulator"
 )
 
 //go:embed logo.png
 var iconData []byte
 
+var iconGenerator = icons.NewGenerator(config.GetIconsDir(), config.GetGeneratedIconsDir())
+
 func main() {
 	systray.Run(onReady, onExit)
 }
@@ -58,78 +65,130 @@ func onReady() {
 		}
 	}()
 	
-	dev := ulanzid200.New(
+	var transport ulanzid200.Transport = ulanzid200.NewHIDTransport()
+	hotplug, err := ulanzid200.NewNetlinkHotplugSource()
+	if err != nil {
+		fmt.Println("Hotplug недоступен, используется опрос устройств:", err)
+	}
+	if serial := config.GetEmulatorSerial(); serial != "" {
+		fmt.Println("Используется эмулятор пульта:", serial)
+		emu := emulator.New(serial)
+		transport = emu
+		if hotplug != nil {
+			hotplug.Close()
+		}
+		hotplug = emu.Hotplug()
+		go saveEmulatorSnapshots(emu)
+	}
+	manager := ulanzid200.NewDeviceManager(
+		transport,
 		ulanzid200.CLOCK,
-    config.GetIconsDir(),
+		config.GetIconsDir(),
 		config.GetTempDir(),
 	)
+	if hotplug != nil {
+		manager.SetHotplugSource(hotplug)
+	}
 	appDetector := appdetector.New(config.GetSettingsPath(), config.GetIconsDir())
-	var settings *appdetector.Application
-	var settingsNP *appdetector.Application
-	var stopProcessDetect bool
-	go func() {
-		processChangedChan := appDetector.ProcessChangedChan()
-		refreshChan := dev.RefreshChan()
-		for {
-			select {
-			case settings = <-processChangedChan:
-			case <-refreshChan:
-				if stopProcessDetect {
-					setSettings(dev, settingsNP)
-				}
-			}
-			if !stopProcessDetect {
-				setSettings(dev, settings)
-			}
-		}
-	}()
-	go func() {
-		keyPressedChan := dev.KeyPressedChan()
-		for {
-			keyPressedEvent := <-keyPressedChan
-			fmt.Printf("keyPressedEvent: %#v\n", keyPressedEvent)
-			var command string
-			if stopProcessDetect {
-				command = settingsNP.Buttons[keyPressedEvent.Index].Command
-			} else {
-				command = settings.Buttons[keyPressedEvent.Index].Command
-			}
-			if command != "" {
-				fmt.Printf("command: %#v\n", command)
-				if strings.HasPrefix(command, "@") {
-					command=strings.TrimSpace(strings.TrimPrefix(command, "@"))
-					fmt.Printf("command: %#v\n", command)
-					if command == "" {
-						stopProcessDetect = false
-						setSettings(dev, settings)
-					} else {
-						stopProcessDetect = true
-						settingsNP = appdetector.GetSettingsForProcess(command)
-						setSettings(dev, settingsNP)
-					}
-				} else if strings.HasPrefix(command, "$") {
-					command=strings.TrimSpace(strings.TrimPrefix(command, "$"))
-					appdetector.FocusOrRun(command)
-				} else {
-					_ = exec.Command("sh", "-c", command).Start()
-				}
-			}
-		}
-	}()
+	decks := newDeckRegistry()
+	go decks.run(appDetector.ProcessChangedChan(), manager.DeviceEventChan())
 	appDetector.Start()
-	dev.Start()
+	manager.Start()
 }
-func setSettings(dev *ulanzid200.UlanziD200Device, settings *appdetector.Application) {
+
+// buttonView — изменяемая часть кнопки: состояние переключателя, подпись
+// и кадр анимации, заменяющий иконку
+type buttonView struct {
+	state int
+	text  string
+	icon  string
+}
+
+// setSettings показывает страницу page из pages. Кнопки навигации без
+// подписи показывают номер текущей страницы.
+func setSettings(dev *ulanzid200.UlanziD200Device, settings []appdetector.Button, views []buttonView, page, pages int) {
 	buttons := make(map[int]ulanzid200.Button)
-	for i, button := range settings.Buttons {
-		fmt.Println(i, button.Name)
-		buttons[i] = ulanzid200.Button{
-			Icon: button.Icon,
+	for i, button := range settings {
+		buttons[i] = deckButton(button, views[i])
+		if button.Name == "" && button.Text == "" && pages > 1 && button.Command.Type == actions.Page {
+			buttons[i] = ulanzid200.Button{
+				Name: fmt.Sprintf("%d/%d", page+1, pages),
+				Icon: resolveIcon(button.Icon, button.IconSpec),
+			}
 		}
 	}
 	dev.SetButtons(buttons, false)
 }
 
+// updateButton обновляет на пульте одну кнопку, не трогая остальные
+func updateButton(dev *ulanzid200.UlanziD200Device, index int, button appdetector.Button, view buttonView) {
+	dev.SetButtons(map[int]ulanzid200.Button{index: deckButton(button, view)}, true)
+}
+
+// deckButton собирает кнопку для пульта. Подпись — name кнопки или состояния,
+// а если задан шаблон text, то его значение для всех состояний.
+func deckButton(button appdetector.Button, view buttonView) ulanzid200.Button {
+	if !button.IsToggle() {
+		icon := view.icon
+		if icon == "" {
+			icon = resolveIcon(button.Icon, button.IconSpec)
+		}
+		name := button.Name
+		if button.Text != "" {
+			name = view.text
+		}
+		return ulanzid200.Button{
+			Name: name,
+			Icon: icon,
+		}
+	}
+	views := make([]ulanzid200.ButtonView, 0, len(button.States))
+	for i := range button.States {
+		state := button.GetState(i)
+		icon := view.icon
+		if icon == "" {
+			icon = resolveIcon(state.Icon, state.IconSpec)
+		}
+		name := state.Name
+		if button.Text != "" {
+			name = view.text
+		}
+		views = append(views, ulanzid200.ButtonView{
+			Name: name,
+			Icon: icon,
+		})
+	}
+	return ulanzid200.Button{
+		State: view.state,
+		Views: views,
+	}
+}
+
+// resolveIcon возвращает иконку кнопки: нарисованную по spec, если он задан,
+// иначе файл icon
+func resolveIcon(icon string, spec *icons.Spec) string {
+	if spec == nil {
+		return icon
+	}
+	path, err := iconGenerator.Generate(*spec)
+	if err != nil {
+		fmt.Println("Ошибка генерации иконки:", err)
+		return icon
+	}
+	return path
+}
+
+// saveEmulatorSnapshots сохраняет картинку экрана эмулятора после каждого изменения
+func saveEmulatorSnapshots(emu *emulator.Emulator) {
+	path := config.GetEmulatorSnapshotPath()
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	for range emu.Changed() {
+		if err := emu.SavePNG(path); err != nil {
+			fmt.Println("Ошибка сохранения снимка эмулятора:", err)
+		}
+	}
+}
+
 func onExit() {
 	fmt.Println("Завершение работы")
 }
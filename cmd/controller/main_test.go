@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+)
+
+func TestDeckButton(t *testing.T) {
+	toggle := appdetector.Button{
+		Name: "Mic",
+		Icon: "mic.png",
+		States: []appdetector.ButtonState{
+			{Name: "On"},
+			{Icon: "mic-off.png"},
+		},
+	}
+	templated := toggle
+	templated.Text = "{{.state}}"
+
+	tests := []struct {
+		name   string
+		button appdetector.Button
+		view   buttonView
+		want   ulanzid200.Button
+	}{
+		{"обычная кнопка", appdetector.Button{Name: "Copy", Icon: "copy.png"}, buttonView{},
+			ulanzid200.Button{Name: "Copy", Icon: "copy.png"}},
+		{"шаблон вместо name", appdetector.Button{Name: "CPU", Icon: "cpu.png", Text: "{{.cpu}}%"}, buttonView{text: "12%"},
+			ulanzid200.Button{Name: "12%", Icon: "cpu.png"}},
+		{"подпись по шаблону", appdetector.Button{Icon: "cpu.png", Text: "{{.cpu}}%"}, buttonView{text: "12%"},
+			ulanzid200.Button{Name: "12%", Icon: "cpu.png"}},
+		{"кадр анимации", appdetector.Button{Icon: "a.png"}, buttonView{icon: "/tmp/frame.png"},
+			ulanzid200.Button{Icon: "/tmp/frame.png"}},
+		{"подписи состояний", toggle, buttonView{state: 1},
+			ulanzid200.Button{State: 1, Views: []ulanzid200.ButtonView{
+				{Name: "On", Icon: "mic.png"},
+				{Name: "Mic", Icon: "mic-off.png"},
+			}}},
+		{"шаблон вместо подписей состояний", templated, buttonView{state: 1, text: "1"},
+			ulanzid200.Button{State: 1, Views: []ulanzid200.ButtonView{
+				{Name: "1", Icon: "mic.png"},
+				{Name: "1", Icon: "mic-off.png"},
+			}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deckButton(tt.button, tt.view); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deckButton = %+v, ожидалось %+v", got, tt.want)
+			}
+		})
+	}
+}
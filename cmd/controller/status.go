@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+)
+
+// Сколько может выполняться команда проверки состояния
+const statusTimeout = 5 * time.Second
+
+func (d *deck) pollStatus(stop chan struct{}, index int, button *appdetector.Button) {
+	ticker := time.NewTicker(button.Status.Interval())
+	defer ticker.Stop()
+	for {
+		state, ok := readStatus(button.Status)
+		if ok {
+			d.setStatus(stop, index, button, state)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setStatus меняет состояние кнопки и, если оно изменилось,
+// обновляет на пульте только эту кнопку
+func (d *deck) setStatus(stop chan struct{}, index int, button *appdetector.Button, state int) {
+	if state < 0 || state >= len(button.States) {
+		fmt.Printf("Состояние %d вне диапазона для кнопки %q\n", state, button.Name)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	if d.states[button] == state {
+		return
+	}
+	d.states[button] = state
+	if d.dev != nil {
+		updateButton(d.dev, index, *button, d.view(button))
+	}
+}
+
+// readStatus выполняет проверку и возвращает выбранное состояние
+func readStatus(status *appdetector.ButtonStatus) (int, bool) {
+	if status.File != "" {
+		data, err := os.ReadFile(status.File)
+		if err != nil {
+			fmt.Println("Ошибка чтения файла состояния:", err)
+			return 0, false
+		}
+		return status.Resolve(string(data), 0)
+	}
+	if status.Command == "" {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "sh", "-c", status.Command).Output()
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			fmt.Println("Ошибка команды состояния:", err)
+			return 0, false
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	return status.Resolve(string(output), exitCode)
+}
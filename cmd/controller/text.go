@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	appdetector "github.com/bjaka-max/dispeys/pkg/app_detector"
+	hwmonitor "github.com/bjaka-max/dispeys/pkg/hw_monitor"
+	labeltemplate "github.com/bjaka-max/dispeys/pkg/label_template"
+)
+
+// pollText пересчитывает подпись кнопки по шаблону button.Text
+func (d *deck) pollText(stop chan struct{}, index int, button *appdetector.Button) {
+	tmpl, err := labeltemplate.Parse(button.Text)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if tmpl.IsStatic() {
+		d.setText(stop, index, button, button.Text)
+		return
+	}
+	fields := tmpl.Fields()
+	ticker := time.NewTicker(button.TextInterval())
+	defer ticker.Stop()
+	for {
+		text, err := tmpl.Execute(d.textData(button, fields))
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			d.setText(stop, index, button, text)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setText запоминает подпись и, если она изменилась, обновляет кнопку на пульте
+func (d *deck) setText(stop chan struct{}, index int, button *appdetector.Button, text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	if old, ok := d.texts[button]; ok && old == text {
+		return
+	}
+	d.texts[button] = text
+	if d.dev != nil {
+		updateButton(d.dev, index, *button, d.view(button))
+	}
+}
+
+// textData собирает данные для шаблона подписи. Загрузка процессора,
+// памяти и видеокарты измеряется, только если шаблон на них ссылается.
+func (d *deck) textData(button *appdetector.Button, fields map[string]bool) map[string]interface{} {
+	d.mu.Lock()
+	data := map[string]interface{}{
+		"name":    button.Name,
+		"serial":  d.serial,
+		"process": d.process,
+		"state":   d.states[button],
+	}
+	d.mu.Unlock()
+	if fields["cpu"] {
+		if value, err := hwmonitor.GetCPUUsage(); err == nil {
+			data["cpu"] = int(math.Round(value))
+		}
+	}
+	if fields["mem"] {
+		if value, err := hwmonitor.GetMemoryUsage(); err == nil {
+			data["mem"] = int(math.Round(value))
+		}
+	}
+	if fields["gpu"] {
+		if value, err := hwmonitor.GetGPUUsage(); err == nil {
+			data["gpu"] = int(math.Round(value))
+		}
+	}
+	return data
+}
@@ -2,7 +2,13 @@ module github.com/bjaka-max/dispeys
 
 go 1.24.4
 
-require github.com/gotk3/gotk3 v0.6.4
+require (
+	github.com/gotk3/gotk3 v0.6.4
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+	golang.org/x/image v0.25.0
+	golang.org/x/sys v0.34.0
+)
 
 require (
 	github.com/ebitengine/purego v0.8.4 // indirect
@@ -12,6 +18,7 @@ require (
 	github.com/tklauser/go-sysconf v0.3.15 // indirect
 	github.com/tklauser/numcpus v0.10.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
+	golang.org/x/net v0.38.0 // indirect
 )
 
 require (
@@ -22,12 +29,12 @@ require (
 	github.com/getlantern/hex v0.0.0-20190417191902-c6586a6fe0b7 // indirect
 	github.com/getlantern/hidden v0.0.0-20190325191715-f02dbb02be55 // indirect
 	github.com/getlantern/ops v0.0.0-20190325191751-d70cb0d6f85f // indirect
-	github.com/getlantern/systray v1.2.2 // indirect
+	github.com/getlantern/systray v1.2.2
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
 	github.com/go-gl/glfw v0.0.0-20231124074035-2de0cf0c80af // indirect
 	github.com/go-stack/stack v1.8.0 // indirect
-	github.com/karalabe/hid v1.0.0 // indirect
+	github.com/karalabe/hid v1.0.0
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
 	github.com/shirou/gopsutil/v4 v4.25.7
-	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Типы действий
+const (
+	// Команда оболочки: sh -c command
+	Shell = "shell"
+	// Переключение на окно программы или её запуск
+	FocusOrRun = "focus_or_run"
+	// Профиль, который показывается независимо от активного окна;
+	// пустой профиль возвращает выбор профиля по окну
+	SwitchProfile = "switch_profile"
+	// Переход по страницам: next, prev, back, home, номер или имя страницы
+	Page = "page"
+	// Нажатие клавиш в формате xdotool key, например "ctrl+c", и ввод
+	// текста text: сначала печатается текст, затем нажимаются клавиши
+	Keys = "keys"
+	// Открытие адреса в программе по умолчанию
+	OpenURL = "open_url"
+	// Пауза на delay_ms, обычно между шагами макроса
+	Wait = "wait"
+	// Шаги steps по порядку. С abort_on_error первый неудачный шаг
+	// прерывает макрос, иначе ошибка выводится и выполняются следующие.
+	// Шаг с if_status выполняется, только если предыдущий выполненный
+	// шаг завершился с этим результатом.
+	Macro = "macro"
+)
+
+// Результат шага макроса для if_status
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Action — действие кнопки, жеста или аккорда. В settings.json задаётся
+// объектом {"type": "shell", "command": "..."} или строкой в старом виде:
+// "@профиль", "$программа", "#страница", остальное — команда оболочки.
+// Команда "xdotool key ..." без параметров выполняется как действие keys.
+// Массив действий — короткая запись макроса.
+type Action struct {
+	Type    string `json:"type"`
+	Command string `json:"command,omitempty"`
+	Program string `json:"program,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	Page    string `json:"page,omitempty"`
+	Keys    string `json:"keys,omitempty"`
+	Text    string `json:"text,omitempty"`
+	URL     string `json:"url,omitempty"`
+	DelayMs int    `json:"delay_ms,omitempty"`
+
+	Steps        []Action `json:"steps,omitempty"`
+	AbortOnError bool     `json:"abort_on_error,omitempty"`
+	// Сколько ждать завершения действия; по истечении команда
+	// останавливается, а действие считается неудачным
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+	// Не запускать команду shell, пока не завершилась такая же
+	SingleInstance bool `json:"single_instance,omitempty"`
+	// Подсветить кнопку зелёным или красным по результату действия
+	Feedback bool `json:"feedback,omitempty"`
+	// Только для шага макроса: ok или error — выполнять шаг, только если
+	// предыдущий выполненный шаг завершился так
+	IfStatus string `json:"if_status,omitempty"`
+	// Действие, которое выполняется, если это не удалось. Неудачный шаг
+	// с on_error считается обработанным и не прерывает макрос.
+	OnError *Action `json:"on_error,omitempty"`
+
+	// Строка, из которой разобрано действие: сохраняется в настройки как была
+	shorthand string
+	// Макрос записан массивом
+	list bool
+}
+
+// Parse разбирает действие, записанное строкой
+func Parse(s string) Action {
+	action := Action{shorthand: s}
+	switch {
+	case s == "":
+		return Action{}
+	case strings.HasPrefix(s, "@"):
+		action.Type = SwitchProfile
+		action.Profile = strings.TrimSpace(strings.TrimPrefix(s, "@"))
+	case strings.HasPrefix(s, "$"):
+		action.Type = FocusOrRun
+		action.Program = strings.TrimSpace(strings.TrimPrefix(s, "$"))
+	case strings.HasPrefix(s, "#"):
+		action.Type = Page
+		action.Page = strings.TrimSpace(strings.TrimPrefix(s, "#"))
+	case xdotoolKeys(s) != "":
+		action.Type = Keys
+		action.Keys = xdotoolKeys(s)
+	default:
+		action.Type = Shell
+		action.Command = s
+	}
+	return action
+}
+
+// IsEmpty — действие не задано
+func (a *Action) IsEmpty() bool {
+	return a.Type == ""
+}
+
+// Validate проверяет, что тип действия известен и заданы нужные ему поля
+func (a *Action) Validate() error {
+	if a.IfStatus != "" {
+		return fmt.Errorf("if_status задаётся только у шага макроса")
+	}
+	return a.validate()
+}
+
+// validateStep проверяет шаг макроса
+func (a *Action) validateStep() error {
+	switch a.IfStatus {
+	case "", StatusOK, StatusError:
+	default:
+		return fmt.Errorf("неверное значение if_status %q: ожидается %s или %s", a.IfStatus, StatusOK, StatusError)
+	}
+	return a.validate()
+}
+
+func (a *Action) validate() error {
+	required := func(name, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("для действия %s не задано поле %s", a.Type, name)
+		}
+		return nil
+	}
+	if a.TimeoutMs < 0 {
+		return fmt.Errorf("отрицательный timeout_ms у действия %s", a.Type)
+	}
+	if a.OnError != nil {
+		if a.OnError.IsEmpty() {
+			return fmt.Errorf("on_error: пустое действие")
+		}
+		if err := a.OnError.Validate(); err != nil {
+			return fmt.Errorf("on_error: %w", err)
+		}
+	}
+	switch a.Type {
+	case "", SwitchProfile:
+		return nil
+	case Wait:
+		if a.DelayMs <= 0 {
+			return fmt.Errorf("для действия %s не задано поле delay_ms", a.Type)
+		}
+		return nil
+	case Macro:
+		if len(a.Steps) == 0 {
+			return fmt.Errorf("в макросе нет шагов")
+		}
+		for i := range a.Steps {
+			if a.Steps[i].IsEmpty() {
+				return fmt.Errorf("шаг %d: пустое действие", i+1)
+			}
+			if err := a.Steps[i].validateStep(); err != nil {
+				return fmt.Errorf("шаг %d: %w", i+1, err)
+			}
+		}
+		return nil
+	case Shell:
+		return required("command", a.Command)
+	case FocusOrRun:
+		return required("program", a.Program)
+	case Page:
+		return required("page", a.Page)
+	case Keys:
+		return validateKeys(a)
+	case OpenURL:
+		return required("url", a.URL)
+	}
+	return fmt.Errorf("неизвестный тип действия %q", a.Type)
+}
+
+func (a Action) String() string {
+	if a.shorthand != "" {
+		return a.shorthand
+	}
+	data, _ := json.Marshal(a)
+	return string(data)
+}
+
+// action — Action без своих методов JSON
+type action Action
+
+func (a *Action) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*a = Action{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*a = Parse(s)
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var steps []Action
+		if err := json.Unmarshal(data, &steps); err != nil {
+			return err
+		}
+		*a = Action{Type: Macro, Steps: steps, list: true}
+		return nil
+	}
+	// Неизвестные поля — скорее всего опечатка, молча их не пропускаем
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var result action
+	if err := decoder.Decode(&result); err != nil {
+		return fmt.Errorf("ошибка в действии: %w", err)
+	}
+	*a = Action(result)
+	return nil
+}
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	if a.list {
+		return json.Marshal(a.Steps)
+	}
+	if a.shorthand != "" || a.IsEmpty() {
+		return json.Marshal(a.shorthand)
+	}
+	return json.Marshal(action(a))
+}
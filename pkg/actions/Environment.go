@@ -0,0 +1,32 @@
+package actions
+
+import "context"
+
+// Environment — окружение, в котором запускаются команды действия
+type Environment struct {
+	// Переменные в виде "KEY=value"
+	Vars []string
+	// Рабочий каталог
+	Dir string
+	// Lazy дописывает к Vars переменные, которые дорого считать заранее.
+	// Вызывается, только когда действие действительно запускает программу.
+	Lazy func() []string
+}
+
+type environmentKey struct{}
+
+// WithEnvironment передаёт окружение командам, которые запустит Registry.Run
+// с этим контекстом, в том числе шагам макросов
+func WithEnvironment(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+// EnvironmentFrom возвращает окружение из контекста вместе с переменными Lazy
+func EnvironmentFrom(ctx context.Context) Environment {
+	env, _ := ctx.Value(environmentKey{}).(Environment)
+	if env.Lazy != nil {
+		env.Vars = append(append([]string(nil), env.Vars...), env.Lazy()...)
+		env.Lazy = nil
+	}
+	return env
+}
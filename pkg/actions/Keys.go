@@ -0,0 +1,108 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bjaka-max/dispeys/pkg/supervisor"
+	"github.com/bjaka-max/dispeys/pkg/uinput"
+)
+
+func validateKeys(a *Action) error {
+	if strings.TrimSpace(a.Keys) == "" && a.Text == "" {
+		return fmt.Errorf("для действия %s не задано поле keys или text", a.Type)
+	}
+	if strings.TrimSpace(a.Keys) != "" {
+		if _, err := uinput.ParseKeys(a.Keys); err != nil {
+			return err
+		}
+	}
+	if _, err := uinput.TextCombos(a.Text); err != nil {
+		return err
+	}
+	return nil
+}
+
+// xdotoolKeys возвращает клавиши из команды вида "xdotool key ctrl+c",
+// если её можно выполнить без xdotool, иначе пустую строку
+func xdotoolKeys(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) < 3 || fields[0] != "xdotool" || fields[1] != "key" {
+		return ""
+	}
+	for _, field := range fields[2:] {
+		if strings.HasPrefix(field, "-") {
+			return ""
+		}
+	}
+	keys := strings.Join(fields[2:], " ")
+	if _, err := uinput.ParseKeys(keys); err != nil {
+		return ""
+	}
+	return keys
+}
+
+// KeysHandler нажимает клавиши действия keys на keyboard
+func KeysHandler(keyboard *uinput.Keyboard) Handler {
+	return func(ctx context.Context, action *Action) error {
+		if action.Text != "" {
+			if err := keyboard.Type(ctx, action.Text); err != nil {
+				return err
+			}
+		}
+		if strings.TrimSpace(action.Keys) != "" {
+			return keyboard.Keys(ctx, action.Keys)
+		}
+		return nil
+	}
+}
+
+var (
+	keyboardOnce sync.Once
+	keyboard     *uinput.Keyboard
+)
+
+// systemKeyboard открывает виртуальную клавиатуру при первом нажатии.
+// Если uinput недоступен, возвращает nil.
+func systemKeyboard() *uinput.Keyboard {
+	keyboardOnce.Do(func() {
+		device, err := uinput.Open("dispeys keyboard")
+		if err != nil {
+			fmt.Println("Клавиши будут нажиматься через xdotool:", err)
+			return
+		}
+		keyboard = uinput.NewKeyboard(device)
+	})
+	return keyboard
+}
+
+func (r *Registry) runKeys(ctx context.Context, action *Action) error {
+	if keyboard := systemKeyboard(); keyboard != nil {
+		return KeysHandler(keyboard)(ctx, action)
+	}
+	return r.runXdotool(ctx, action)
+}
+
+// runXdotool — запасной вариант для систем без доступа к /dev/uinput
+func (r *Registry) runXdotool(ctx context.Context, action *Action) error {
+	if action.Text != "" {
+		err := r.supervisor.Run(ctx, supervisor.Spec{
+			Name: "xdotool",
+			Path: "xdotool",
+			Args: []string{"type", "--", action.Text},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if keys := strings.Fields(action.Keys); len(keys) > 0 {
+		return r.supervisor.Run(ctx, supervisor.Spec{
+			Name: "xdotool",
+			Path: "xdotool",
+			Args: append([]string{"key", "--"}, keys...),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bjaka-max/dispeys/pkg/uinput"
+)
+
+func TestKeysHandler(t *testing.T) {
+	w := &uinput.FakeWriter{}
+	keyboard := uinput.NewKeyboard(w)
+	keyboard.Delay = 0
+	handler := KeysHandler(keyboard)
+
+	// Сначала печатается текст, затем нажимаются клавиши
+	if err := handler(context.Background(), &Action{Type: Keys, Text: "a", Keys: "Return"}); err != nil {
+		t.Fatal(err)
+	}
+	var codes []uint16
+	for _, event := range w.Events() {
+		if event.Type == uinput.EV_KEY && event.Value == 1 {
+			codes = append(codes, event.Code)
+		}
+	}
+	if len(codes) != 2 || codes[0] != 30 || codes[1] != uinput.KEY_ENTER {
+		t.Errorf("нажаты %v", codes)
+	}
+
+	w.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := handler(ctx, &Action{Type: Keys, Text: "abc", Keys: "ctrl+c"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ошибка %v", err)
+	}
+	if events := w.Events(); len(events) != 0 {
+		t.Errorf("после отмены отправлены события %v", events)
+	}
+}
@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/supervisor"
+)
+
+// Handler выполняет действие своего типа и возвращается, когда оно
+// завершилось. По отмене ctx (таймаут шага) действие прерывается.
+type Handler func(ctx context.Context, action *Action) error
+
+// Registry связывает типы действий с обработчиками
+type Registry struct {
+	handlers map[string]Handler
+	// Запускает и дожидается процессов shell, open_url и xdotool
+	supervisor *supervisor.Supervisor
+}
+
+// NewRegistry создаёт реестр с обработчиками shell, keys, open_url, wait
+// и macro. Остальные типы зависят от состояния пульта и регистрируются
+// контроллером.
+func NewRegistry() *Registry {
+	r := &Registry{
+		handlers:   make(map[string]Handler),
+		supervisor: supervisor.Default,
+	}
+	r.Register(Shell, r.runShell)
+	r.Register(Keys, r.runKeys)
+	r.Register(OpenURL, r.runOpenURL)
+	r.Register(Wait, runWait)
+	r.Register(Macro, r.runMacro)
+	return r
+}
+
+// Register задаёт обработчик для типа действий, заменяя прежний
+func (r *Registry) Register(actionType string, handler Handler) {
+	r.handlers[actionType] = handler
+}
+
+// Run выполняет действие с учётом его timeout_ms. Пустое действие ничего
+// не делает. Если действие не удалось, выполняется его on_error, а
+// возвращается исходная ошибка.
+func (r *Registry) Run(ctx context.Context, action *Action) error {
+	if action.IsEmpty() {
+		return nil
+	}
+	err := r.run(ctx, action)
+	if err != nil && action.OnError != nil {
+		// Таймаут действия на on_error не распространяется
+		if onErr := r.Run(ctx, action.OnError); onErr != nil {
+			fmt.Println("Ошибка в on_error:", onErr)
+		}
+	}
+	return err
+}
+
+func (r *Registry) run(ctx context.Context, action *Action) error {
+	handler, ok := r.handlers[action.Type]
+	if !ok {
+		return fmt.Errorf("нет обработчика для действия %q", action.Type)
+	}
+	if action.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(action.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	if err := handler(ctx, action); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s: превышено время ожидания", action.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) runMacro(ctx context.Context, action *Action) error {
+	// Результат последнего выполненного шага для if_status
+	status := StatusOK
+	for i := range action.Steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		step := &action.Steps[i]
+		if step.IfStatus != "" && step.IfStatus != status {
+			continue
+		}
+		err := r.Run(ctx, step)
+		if err == nil {
+			status = StatusOK
+			continue
+		}
+		status = StatusError
+		err = fmt.Errorf("шаг %d: %w", i+1, err)
+		if action.AbortOnError && step.OnError == nil {
+			return err
+		}
+		fmt.Println("Ошибка в макросе:", err)
+	}
+	return nil
+}
+
+func runWait(ctx context.Context, action *Action) error {
+	timer := time.NewTimer(time.Duration(action.DelayMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runShell ждёт завершения команды. С single_instance команда не
+// запускается, пока не завершилась такая же, запущенная раньше.
+func (r *Registry) runShell(ctx context.Context, action *Action) error {
+	env := EnvironmentFrom(ctx)
+	spec := supervisor.Spec{
+		Name: shortName(action.Command),
+		Path: "sh",
+		Args: []string{"-c", action.Command},
+		Env:  env.Vars,
+		Dir:  env.Dir,
+	}
+	if action.SingleInstance {
+		spec.Key = action.Command
+	}
+	return r.supervisor.Run(ctx, spec)
+}
+
+func (r *Registry) runOpenURL(ctx context.Context, action *Action) error {
+	env := EnvironmentFrom(ctx)
+	return r.supervisor.Run(ctx, supervisor.Spec{
+		Name: "xdg-open",
+		Path: "xdg-open",
+		Args: []string{action.URL},
+		Env:  env.Vars,
+		Dir:  env.Dir,
+	})
+}
+
+// shortName — подпись команды в логе
+func shortName(command string) string {
+	const max = 32
+	if runes := []rune(command); len(runes) > max {
+		return string(runes[:max]) + "…"
+	}
+	return command
+}
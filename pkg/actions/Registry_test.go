@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRegistry выполняет действия типа "test": command "fail" завершается
+// ошибкой, остальные успешно. Выполненные команды записываются в calls.
+func testRegistry(calls *[]string) *Registry {
+	r := NewRegistry()
+	r.Register("test", func(ctx context.Context, action *Action) error {
+		*calls = append(*calls, action.Command)
+		if strings.HasPrefix(action.Command, "fail") {
+			return errors.New(action.Command)
+		}
+		return nil
+	})
+	return r
+}
+
+func TestMacroConditions(t *testing.T) {
+	tests := []struct {
+		name  string
+		macro string
+		calls []string
+		err   bool
+	}{
+		{"все шаги", `{"type": "macro", "steps": [
+			{"type": "test", "command": "a"},
+			{"type": "test", "command": "fail"},
+			{"type": "test", "command": "b"}]}`,
+			[]string{"a", "fail", "b"}, false},
+		{"abort_on_error", `{"type": "macro", "abort_on_error": true, "steps": [
+			{"type": "test", "command": "fail"},
+			{"type": "test", "command": "b"}]}`,
+			[]string{"fail"}, true},
+		{"if_status по результату предыдущего шага", `{"type": "macro", "steps": [
+			{"type": "test", "command": "fail"},
+			{"type": "test", "command": "on-ok", "if_status": "ok"},
+			{"type": "test", "command": "on-error", "if_status": "error"},
+			{"type": "test", "command": "after-recovery", "if_status": "ok"}]}`,
+			[]string{"fail", "on-error", "after-recovery"}, false},
+		{"пропущенный шаг не меняет результат", `{"type": "macro", "steps": [
+			{"type": "test", "command": "a"},
+			{"type": "test", "command": "skipped", "if_status": "error"},
+			{"type": "test", "command": "b", "if_status": "ok"}]}`,
+			[]string{"a", "b"}, false},
+		{"on_error не прерывает макрос", `{"type": "macro", "abort_on_error": true, "steps": [
+			{"type": "test", "command": "fail", "on_error": {"type": "test", "command": "cleanup"}},
+			{"type": "test", "command": "b", "if_status": "error"},
+			{"type": "test", "command": "fail-again"},
+			{"type": "test", "command": "never"}]}`,
+			[]string{"fail", "cleanup", "b", "fail-again"}, true},
+		{"on_error не вызывается при успехе", `{"type": "test", "command": "a",
+			"on_error": {"type": "test", "command": "cleanup"}}`,
+			[]string{"a"}, false},
+		{"on_error у действия кнопки", `{"type": "test", "command": "fail",
+			"on_error": {"type": "test", "command": "cleanup"}}`,
+			[]string{"fail", "cleanup"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action Action
+			if err := json.Unmarshal([]byte(tt.macro), &action); err != nil {
+				t.Fatal(err)
+			}
+			var calls []string
+			err := testRegistry(&calls).Run(context.Background(), &action)
+			if (err != nil) != tt.err {
+				t.Errorf("ошибка %v, ожидалась: %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("выполнено %q, ожидалось %q", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestValidateConditions(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		err    string
+	}{
+		{"шаги с условиями", `{"type": "macro", "steps": [
+			{"type": "shell", "command": "make", "on_error": {"type": "shell", "command": "notify-send fail"}},
+			{"type": "page", "page": "next", "if_status": "ok"}]}`, ""},
+		{"неверный if_status", `{"type": "macro", "steps": [
+			{"type": "wait", "delay_ms": 10, "if_status": "failed"}]}`, `шаг 1: неверное значение if_status "failed"`},
+		{"if_status вне макроса", `{"type": "shell", "command": "true", "if_status": "ok"}`,
+			"if_status задаётся только у шага макроса"},
+		{"пустой on_error", `{"type": "shell", "command": "true", "on_error": {"type": ""}}`,
+			"on_error: пустое действие"},
+		{"неверный on_error", `{"type": "macro", "steps": [
+			{"type": "shell", "command": "true", "on_error": {"type": "open_url"}}]}`,
+			"шаг 1: on_error: для действия open_url не задано поле url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action Action
+			if err := json.Unmarshal([]byte(tt.action), &action); err != nil {
+				t.Fatal(err)
+			}
+			err := action.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("ошибка %v, ожидалось %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentLazy(t *testing.T) {
+	lazyCalls := 0
+	ctx := WithEnvironment(context.Background(), Environment{
+		Vars: []string{"DISPEYS_PROFILE=editor"},
+		Dir:  t.TempDir(),
+		Lazy: func() []string {
+			lazyCalls++
+			return []string{"DISPEYS_ACTIVE_PID=42"}
+		},
+	})
+	r := NewRegistry()
+
+	if err := r.Run(ctx, &Action{Type: Wait, DelayMs: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if lazyCalls != 0 {
+		t.Errorf("переменные окна посчитаны для wait: %d", lazyCalls)
+	}
+
+	shell := &Action{Type: Shell, Command: `test "$DISPEYS_PROFILE" = editor && test "$DISPEYS_ACTIVE_PID" = 42`}
+	if err := r.Run(ctx, shell); err != nil {
+		t.Fatalf("команда не получила окружение: %v", err)
+	}
+	if lazyCalls != 1 {
+		t.Errorf("Lazy вызван %d раз", lazyCalls)
+	}
+}
@@ -5,13 +5,14 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
 type AppDetector struct {
 	settingsFilePath   string
 	iconsDirPath       string
-	processChangedChan chan *Application
+	processChangedChan chan string
 	stopped					   bool
 }
 
@@ -21,41 +22,46 @@ func New(
 	return AppDetector{
 		settingsFilePath: SettingsFilePath,
 		iconsDirPath: IconsDirPath,
-		processChangedChan: make(chan *Application),
+		processChangedChan: make(chan string),
 	}
 }
 
 var processName string
 var winID string
 
-func (a *AppDetector) ProcessChangedChan() chan *Application {
+// activeWindow — последнее активное окно, замеченное циклом Start
+var (
+	activeWindowMu sync.Mutex
+	activeWindow   Window
+)
+
+// ProcessChangedChan отдаёт имя процесса, окно которого стало активным.
+// Профиль для него подбирается через GetSettingsForDevice.
+func (a *AppDetector) ProcessChangedChan() chan string {
 	return a.processChangedChan
 }
 
 func (a *AppDetector) Start() {
 	go func() {
 		for {
-			currentProcessName, currentWinID, err := getActiveWindowProcessName(winID)
+			currentProcessName, currentWinID, currentPID, err := getActiveWindowProcessName(winID)
 			if err == nil {
+				if currentProcessName != "" {
+					// Окно сменилось, даже если процесс тот же
+					winID = currentWinID
+					activeWindowMu.Lock()
+					activeWindow = Window{ID: currentWinID, PID: currentPID}
+					activeWindowMu.Unlock()
+				}
 				if currentProcessName != "" && (processName == "" || processName != currentProcessName) {
 					processName = currentProcessName
-					winID = currentWinID
 					_, err = LoadAppSettings(a.settingsFilePath, a.iconsDirPath)
 					if err != nil {
 						fmt.Println(err)
 					}
 					fmt.Printf("%s, %#v\n", processName, AppSettings)
-					settings := GetSettingsForProcess(processName)
-					if settings !=nil {
-						a.processChangedChan <- settings
-
-						select {
-						case a.processChangedChan <- settings:
-							fmt.Println("process changed to ", processName, " done")
-						default:
-							fmt.Println("process changed without receiver")
-						}
-					}
+					a.processChangedChan <- processName
+					fmt.Println("process changed to ", processName, " done")
 				}
 			} else {
 				fmt.Println(err)
@@ -73,7 +79,7 @@ func (a *AppDetector) Stop() {
 	a.stopped = true
 }
 
-func getActiveWindowProcessName(prevWinID string) (processName string, winID string, err error) {
+func getActiveWindowProcessName(prevWinID string) (processName string, winID string, pid string, err error) {
 	// Получаем ID активного окна
 	winIDRaw, err := exec.Command("xdotool", "getactivewindow").Output()
 	if err != nil {
@@ -86,30 +92,64 @@ func getActiveWindowProcessName(prevWinID string) (processName string, winID str
 	}
 
 	// Получаем PID по окну
+	pid, err = windowPID(winID)
+	if err != nil {
+		return
+	}
+
+	// Получаем команду по PID
+	cmdlineRaw, err := exec.Command("ps", "-p", pid, "-o", "comm=").Output()
+	if err != nil {
+		err = fmt.Errorf("не удалось получить имя процесса: %w", err)
+		return
+	}
+	processName = strings.TrimSpace(string(cmdlineRaw))
+
+	return 
+}
+
+// windowPID возвращает PID процесса окна по свойству _NET_WM_PID
+func windowPID(winID string) (string, error) {
 	cmd := exec.Command("xprop", "-id", winID, "_NET_WM_PID")
 	var out bytes.Buffer
 	cmd.Stdout = &out
-	if err = cmd.Run(); err != nil {
-		err = fmt.Errorf("xprop не смог получить PID: %w", err)
-		return
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("xprop не смог получить PID: %w", err)
 	}
 
 	// Парсим PID
 	line := out.String()
 	parts := strings.Split(line, " = ")
 	if len(parts) != 2 {
-		err = fmt.Errorf("неожиданный формат xprop: %s", line)
-		return
+		return "", fmt.Errorf("неожиданный формат xprop: %s", line)
 	}
-	pid := strings.TrimSpace(parts[1])
+	return strings.TrimSpace(parts[1]), nil
+}
 
-	// Получаем команду по PID
-	cmdlineRaw, err := exec.Command("ps", "-p", pid, "-o", "comm=").Output()
+// Window — активное окно в момент нажатия кнопки
+type Window struct {
+	// Десятичный ID, как его выдаёт и принимает xdotool
+	ID  string
+	PID string
+}
+
+// ActiveWindow возвращает окно, которое Start последним видел активным,
+// без запуска xdotool. До первого опроса поля пустые.
+func ActiveWindow() Window {
+	activeWindowMu.Lock()
+	defer activeWindowMu.Unlock()
+	return activeWindow
+}
+
+// WindowTitle возвращает текущий заголовок окна или пустую строку.
+// Заголовок не кешируется: он меняется без смены окна, например у вкладок браузера.
+func WindowTitle(winID string) string {
+	if winID == "" {
+		return ""
+	}
+	title, err := exec.Command("xdotool", "getwindowname", winID).Output()
 	if err != nil {
-		err = fmt.Errorf("не удалось получить имя процесса: %w", err)
-		return
+		return ""
 	}
-	processName = strings.TrimSpace(string(cmdlineRaw))
-
-	return 
-}
\ No newline at end of file
+	return strings.TrimRight(string(title), "\n")
+}
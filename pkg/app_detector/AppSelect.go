@@ -3,15 +3,20 @@ package appdetector
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/bjaka-max/dispeys/pkg/actions"
+	"github.com/bjaka-max/dispeys/pkg/supervisor"
 )
 
 // FocusOrRun: program - имя бинарника, args - аргументы при запуске (если потребуется запустить).
+// Запущенная программа получает окружение и рабочий каталог из ctx (actions.WithEnvironment).
 // Возвращает ошибку в случае проблем.
-func FocusOrRun(program string, args ...string) error {
+func FocusOrRun(ctx context.Context, program string, args ...string) error {
 	// 1) Найти PID'ы процесса
 	pids, err := getPIDs(program)
 	if err != nil {
@@ -27,7 +32,7 @@ func FocusOrRun(program string, args ...string) error {
 	// 3) Если окон нет — запустить приложение
 	if len(winIDs) == 0 {
 		// Попробуем запустить программу
-		if err := startProgram(program, args...); err != nil {
+		if err := startProgram(actions.EnvironmentFrom(ctx), program, args...); err != nil {
 			return fmt.Errorf("startProgram: %w", err)
 		}
 		return nil
@@ -186,17 +191,18 @@ func activateWindow(winid string) error {
 	return nil
 }
 
-func startProgram(program string, args ...string) error {
-	cmd := exec.Command(program, args...)
-	// Отключаем наследование ввода/вывода (демонизируем)
-	cmd.Stdin = nil
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	// Запустить асинхронно
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-	return nil
+// startProgram запускает программу через супервизор: он дождётся её
+// завершения и запишет вывод в лог. Программа живёт дольше действия,
+// поэтому запускается не с его контекстом.
+func startProgram(env actions.Environment, program string, args ...string) error {
+	_, err := supervisor.Default.Start(context.Background(), supervisor.Spec{
+		Name: program,
+		Path: program,
+		Args: args,
+		Env:  env.Vars,
+		Dir:  env.Dir,
+	})
+	return err
 }
 
 func indexOf(slice []string, val string) int {
@@ -8,23 +8,298 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/actions"
+	"github.com/bjaka-max/dispeys/pkg/icons"
 )
 
 type Settings struct {
 	lastModifiedTime   time.Time
 	Applications map[string]*Application
+	Devices map[string]*DeviceProfile
+}
+
+// Ключ settings.json с привязками профилей к пультам по серийному номеру
+const devicesKey = "devices"
+
+// DeviceProfile — настройки конкретного пульта
+type DeviceProfile struct {
+	// Профиль, который всегда показывается на пульте, независимо от активного окна
+	Profile string                 `json:"profile,omitempty"`
+	// Переопределение профилей для процессов: имя процесса -> имя профиля
+	Applications map[string]string `json:"applications,omitempty"`
 }
 
 type Button struct {
+	// Подпись кнопки на пульте; у переключателя — подпись состояний без своего name.
+	// Если задан Text, вместо name показывается он.
 	Name string      `json:"name"`
 	Icon string      `json:"icon"`
-	Command string   `json:"command"`
+	Command actions.Action `json:"command"`
+	// Действия для жестов: down, up, short, long, long_up, double, repeat.
+	// Command выполняется по короткому нажатию, если short не задан.
+	Gestures map[string]actions.Action `json:"gestures,omitempty"`
+	// Пороги жестов в миллисекундах
+	LongPressMs   int `json:"long_press_ms,omitempty"`
+	DoublePressMs int `json:"double_press_ms,omitempty"`
+	RepeatMs      int `json:"repeat_ms,omitempty"`
+	// Кнопки папки: короткое нажатие открывает их вместо текущей страницы,
+	// последняя кнопка пульта в папке занята под «Назад»
+	Buttons []Button `json:"buttons,omitempty"`
+	// Состояния кнопки-переключателя: короткое нажатие выполняет команду
+	// текущего состояния и переходит к следующему
+	States []ButtonState `json:"states,omitempty"`
+	// Периодическая проверка, выбирающая текущее состояние переключателя
+	Status *ButtonStatus `json:"status,omitempty"`
+	// Подпись кнопки — шаблон вида "{{.cpu}}%", пересчитывается раз в TextIntervalMs.
+	// Заменяет name кнопки и всех её состояний.
+	Text string        `json:"text,omitempty"`
+	TextIntervalMs int `json:"text_interval_ms,omitempty"`
+	// Иконка, которую рисует генератор вместо готового файла icon
+	IconSpec *icons.Spec `json:"icon_spec,omitempty"`
+	// Анимация, которая проигрывается вместо иконки, пока кнопка на экране
+	Animation *Animation `json:"animation,omitempty"`
+}
+
+// Animation — анимированный GIF или список кадров
+type Animation struct {
+	Gif string               `json:"gif,omitempty"`
+	Frames []AnimationFrame  `json:"frames,omitempty"`
+	// Задержка кадров, для которых она не указана
+	DelayMs int              `json:"delay_ms,omitempty"`
+}
+
+type AnimationFrame struct {
+	Icon string   `json:"icon"`
+	DelayMs int   `json:"delay_ms,omitempty"`
+}
+
+const DefaultTextIntervalMs = 1000
+
+// TextInterval возвращает период пересчёта подписи
+func (b *Button) TextInterval() time.Duration {
+	if b.TextIntervalMs > 0 {
+		return time.Duration(b.TextIntervalMs) * time.Millisecond
+	}
+	return time.Duration(DefaultTextIntervalMs) * time.Millisecond
+}
+
+// ButtonStatus — команда или файл, вывод которых задаёт состояние кнопки
+type ButtonStatus struct {
+	Command string  `json:"command,omitempty"`
+	File string     `json:"file,omitempty"`
+	IntervalMs int  `json:"interval_ms,omitempty"`
+	// Состояние для вывода (без пробелов по краям), например {"yes": 1}.
+	// Без совпадения вывод читается как номер состояния, а для команды
+	// без вывода номером состояния считается код выхода.
+	Values map[string]int `json:"values,omitempty"`
+}
+
+const DefaultStatusIntervalMs = 1000
+
+// Interval возвращает период проверки
+func (s *ButtonStatus) Interval() time.Duration {
+	if s.IntervalMs > 0 {
+		return time.Duration(s.IntervalMs) * time.Millisecond
+	}
+	return time.Duration(DefaultStatusIntervalMs) * time.Millisecond
+}
+
+// Resolve выбирает состояние по выводу и коду выхода проверки
+func (s *ButtonStatus) Resolve(output string, exitCode int) (int, bool) {
+	output = strings.TrimSpace(output)
+	if state, ok := s.Values[output]; ok {
+		return state, true
+	}
+	if state, err := strconv.Atoi(output); err == nil {
+		return state, true
+	}
+	if output == "" && s.Command != "" {
+		return exitCode, true
+	}
+	return 0, false
+}
+
+// ButtonState — вид и действие кнопки в одном из состояний.
+// Пустые поля берутся из самой кнопки.
+type ButtonState struct {
+	// Подпись в этом состоянии, если у кнопки нет Text
+	Name string    `json:"name"`
+	Icon string    `json:"icon"`
+	Command actions.Action `json:"command"`
+	IconSpec *icons.Spec `json:"icon_spec,omitempty"`
+}
+
+// IsToggle — у кнопки несколько состояний
+func (b *Button) IsToggle() bool {
+	return len(b.States) > 0
+}
+
+// GetState возвращает состояние с номером state с подставленными
+// значениями кнопки по умолчанию
+func (b *Button) GetState(state int) ButtonState {
+	result := ButtonState{Name: b.Name, Icon: b.Icon, Command: b.Command, IconSpec: b.IconSpec}
+	if state < 0 || state >= len(b.States) {
+		return result
+	}
+	if b.States[state].Name != "" {
+		result.Name = b.States[state].Name
+	}
+	if b.States[state].Icon != "" || b.States[state].IconSpec != nil {
+		result.Icon = b.States[state].Icon
+		result.IconSpec = b.States[state].IconSpec
+	}
+	if !b.States[state].Command.IsEmpty() {
+		result.Command = b.States[state].Command
+	}
+	return result
+}
+
+// IsFolder — кнопка открывает вложенный набор кнопок
+func (b *Button) IsFolder() bool {
+	return len(b.Buttons) > 0
+}
+
+// Значения порогов, если жест назначен, а порог не указан
+const (
+	DefaultLongPressMs   = 500
+	DefaultDoublePressMs = 300
+	DefaultRepeatMs      = 200
+)
+
+// GestureCommand возвращает действие для жеста с именем gesture
+func (b *Button) GestureCommand(gesture string) actions.Action {
+	if command, ok := b.Gestures[gesture]; ok {
+		return command
+	}
+	if gesture == "short" {
+		return b.Command
+	}
+	return actions.Action{}
+}
+
+// GestureTimings возвращает пороги жестов. Долгое нажатие, двойное нажатие
+// и автоповтор включаются, только если для них назначены действия: иначе
+// короткое нажатие срабатывало бы с задержкой или терялось при удержании.
+func (b *Button) GestureTimings() (longPress, doublePress, repeat time.Duration) {
+	for _, gesture := range []string{"long", "long_up", "repeat"} {
+		if command := b.GestureCommand(gesture); !command.IsEmpty() {
+			longPress = time.Duration(DefaultLongPressMs) * time.Millisecond
+			if b.LongPressMs > 0 {
+				longPress = time.Duration(b.LongPressMs) * time.Millisecond
+			}
+			break
+		}
+	}
+	if command := b.GestureCommand("double"); !command.IsEmpty() {
+		doublePress = time.Duration(DefaultDoublePressMs) * time.Millisecond
+		if b.DoublePressMs > 0 {
+			doublePress = time.Duration(b.DoublePressMs) * time.Millisecond
+		}
+	}
+	if command := b.GestureCommand("repeat"); !command.IsEmpty() {
+		repeat = time.Duration(DefaultRepeatMs) * time.Millisecond
+		if b.RepeatMs > 0 {
+			repeat = time.Duration(b.RepeatMs) * time.Millisecond
+		}
+	}
+	return
 }
 
 type Application struct {
 	Name string      `json:"name"`
 	Buttons []Button `json:"buttons"`
+	// Дополнительные страницы; если заданы и Buttons, то Buttons — первая страница
+	Pages []Page     `json:"pages,omitempty"`
+	Chords []Chord       `json:"chords,omitempty"`
+	Sequences []Sequence `json:"sequences,omitempty"`
+	// Переменные окружения и рабочий каталог команд профиля.
+	// В cwd раскрываются ~ и $VAR.
+	Env map[string]string `json:"env,omitempty"`
+	Cwd string            `json:"cwd,omitempty"`
+
+	// Имя профиля в settings.json
+	key string
+}
+
+// Key возвращает имя профиля в settings.json
+func (a *Application) Key() string {
+	return a.key
+}
+
+// Environment возвращает окружение, в котором выполняются команды профиля
+func (a *Application) Environment() actions.Environment {
+	env := actions.Environment{Dir: expandPath(a.Cwd)}
+	names := make([]string, 0, len(a.Env))
+	for name := range a.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env.Vars = append(env.Vars, name+"="+a.Env[name])
+	}
+	return env
+}
+
+// expandPath раскрывает ~ в начале пути и переменные окружения
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return os.ExpandEnv(path)
+}
+
+type Page struct {
+	Name string      `json:"name"`
+	Buttons []Button `json:"buttons"`
+}
+
+// GetPages возвращает все страницы профиля, первая — Buttons
+func (a *Application) GetPages() []Page {
+	if len(a.Pages) == 0 {
+		return []Page{{Name: a.Name, Buttons: a.Buttons}}
+	}
+	if len(a.Buttons) == 0 {
+		return a.Pages
+	}
+	return append([]Page{{Name: a.Name, Buttons: a.Buttons}}, a.Pages...)
+}
+
+// FindPage ищет страницу по номеру (с единицы) или по имени. Возвращает -1, если не нашлась.
+func (a *Application) FindPage(ref string) int {
+	pages := a.GetPages()
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 1 && n <= len(pages) {
+			return n - 1
+		}
+		return -1
+	}
+	for i, page := range pages {
+		if page.Name == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// Chord — действие для нескольких кнопок, зажатых одновременно
+type Chord struct {
+	Keys []int     `json:"keys"`
+	Command actions.Action `json:"command"`
+}
+
+// Sequence — действие для кнопок, нажатых по порядку с интервалом не больше WithinMs.
+// Кнопок в последовательности не меньше двух.
+type Sequence struct {
+	Keys []int     `json:"keys"`
+	WithinMs int   `json:"within_ms,omitempty"`
+	Command actions.Action `json:"command"`
 }
 
 //go:embed settings_default.json
@@ -143,12 +418,13 @@ func LoadAppSettings(path, iconPath string) (bool, error) {
 		return false, fmt.Errorf("не удалось прочитать файл: %w", err)
 	}
 
-	var apps map[string]*Application
-	if err := json.Unmarshal(data, &apps); err != nil {
-		return false, fmt.Errorf("ошибка парсинга JSON (ожидается map[string]Application): %w", err)
+	apps, devices, err := parseSettings(data)
+	if err != nil {
+		return false, err
 	}
 
 	AppSettings.Applications = apps
+	AppSettings.Devices = devices
 	AppSettings.lastModifiedTime = modTime
 
 	return true, nil
@@ -159,7 +435,14 @@ func SaveAppSettings(path string) error {
 		AppSettings.Applications = make(map[string]*Application)
 	}
 
-	data, err := json.MarshalIndent(AppSettings.Applications, "", "  ")
+	raw := make(map[string]interface{}, len(AppSettings.Applications)+1)
+	for name, app := range AppSettings.Applications {
+		raw[name] = app
+	}
+	if len(AppSettings.Devices) > 0 {
+		raw[devicesKey] = AppSettings.Devices
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
 	if err != nil {
 		return fmt.Errorf("json marshal error: %w", err)
 	}
@@ -189,6 +472,52 @@ func SaveAppSettings(path string) error {
 	return nil
 }
 
+func parseSettings(data []byte) (map[string]*Application, map[string]*DeviceProfile, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("ошибка парсинга JSON (ожидается map[string]Application): %w", err)
+	}
+
+	var devices map[string]*DeviceProfile
+	if devicesData, ok := raw[devicesKey]; ok {
+		if err := json.Unmarshal(devicesData, &devices); err != nil {
+			return nil, nil, fmt.Errorf("ошибка парсинга %q (ожидается map[string]DeviceProfile): %w", devicesKey, err)
+		}
+		delete(raw, devicesKey)
+	}
+
+	apps := make(map[string]*Application, len(raw))
+	for name, appData := range raw {
+		var app *Application
+		if err := json.Unmarshal(appData, &app); err != nil {
+			return nil, nil, fmt.Errorf("ошибка парсинга профиля %q: %w", name, err)
+		}
+		if app != nil {
+			app.key = name
+		}
+		apps[name] = app
+	}
+	if err := validateSettings(apps); err != nil {
+		return nil, nil, err
+	}
+	return apps, devices, nil
+}
+
+// GetSettingsForDevice подбирает профиль для процесса с учётом
+// привязок пульта с серийным номером serial
+func GetSettingsForDevice(serial, process string) *Application {
+	if device, ok := AppSettings.Devices[serial]; ok && device != nil {
+		name := device.Profile
+		if mapped, ok := device.Applications[process]; ok && name == "" {
+			name = mapped
+		}
+		if result, ok := AppSettings.Applications[name]; ok && name != "" {
+			return result
+		}
+	}
+	return GetSettingsForProcess(process)
+}
+
 func GetSettingsForProcess(process string) (result *Application) {
 	var ok bool
 	if result, ok = AppSettings.Applications[process]; !ok {
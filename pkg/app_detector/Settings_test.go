@@ -0,0 +1,34 @@
+package appdetector
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGestureTimings(t *testing.T) {
+	tests := []struct {
+		name                      string
+		button                    string
+		longPress, double, repeat int
+	}{
+		{"только команда", `{"command": {"type": "shell", "command": "true"}, "long_press_ms": 800}`, 0, 0, 0},
+		{"long", `{"gestures": {"long": {"type": "shell", "command": "true"}}}`, DefaultLongPressMs, 0, 0},
+		{"long_up со своим порогом", `{"gestures": {"long_up": {"type": "shell", "command": "true"}}, "long_press_ms": 800}`, 800, 0, 0},
+		{"repeat включает долгое нажатие", `{"gestures": {"repeat": {"type": "shell", "command": "true"}}}`, DefaultLongPressMs, 0, DefaultRepeatMs},
+		{"double", `{"gestures": {"double": {"type": "shell", "command": "true"}}, "double_press_ms": 250}`, 0, 250, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var button Button
+			if err := json.Unmarshal([]byte(tt.button), &button); err != nil {
+				t.Fatal(err)
+			}
+			longPress, double, repeat := button.GestureTimings()
+			ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+			if longPress != ms(tt.longPress) || double != ms(tt.double) || repeat != ms(tt.repeat) {
+				t.Errorf("пороги %v %v %v, ожидалось %d %d %d мс", longPress, double, repeat, tt.longPress, tt.double, tt.repeat)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package appdetector
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bjaka-max/dispeys/pkg/actions"
+	"github.com/bjaka-max/dispeys/pkg/gestures"
+	labeltemplate "github.com/bjaka-max/dispeys/pkg/label_template"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+)
+
+// validateSettings проверяет действия всех профилей, чтобы опечатка
+// обнаружилась при загрузке настроек, а не при нажатии кнопки
+func validateSettings(apps map[string]*Application) error {
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if apps[name] == nil {
+			continue
+		}
+		if err := apps[name].validate(apps); err != nil {
+			return fmt.Errorf("профиль %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (a *Application) validate(apps map[string]*Application) error {
+	var check, checkRefs func(action actions.Action) error
+	check = func(action actions.Action) error {
+		if err := action.Validate(); err != nil {
+			return err
+		}
+		return checkRefs(action)
+	}
+	// checkRefs проверяет, что профили и страницы, на которые ссылаются
+	// действия, существуют
+	checkRefs = func(action actions.Action) error {
+		if action.OnError != nil {
+			if err := checkRefs(*action.OnError); err != nil {
+				return fmt.Errorf("on_error: %w", err)
+			}
+		}
+		switch action.Type {
+		case actions.Macro:
+			for i, step := range action.Steps {
+				if err := checkRefs(step); err != nil {
+					return fmt.Errorf("шаг %d: %w", i+1, err)
+				}
+			}
+		case actions.SwitchProfile:
+			if _, ok := apps[action.Profile]; action.Profile != "" && !ok {
+				return fmt.Errorf("профиль %q не найден", action.Profile)
+			}
+		case actions.Page:
+			switch action.Page {
+			case "next", "prev", "back", "home":
+			default:
+				if a.FindPage(action.Page) < 0 {
+					return fmt.Errorf("страница %q не найдена", action.Page)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := a.validateEnvironment(); err != nil {
+		return err
+	}
+	for i, page := range a.GetPages() {
+		if err := validateButtons(page.Buttons, check); err != nil {
+			return fmt.Errorf("страница %d: %w", i+1, err)
+		}
+	}
+	for i, chord := range a.Chords {
+		if err := validateKeys(chord.Keys, 2); err != nil {
+			return fmt.Errorf("аккорд %d: %w", i+1, err)
+		}
+		if err := check(chord.Command); err != nil {
+			return fmt.Errorf("аккорд %d: %w", i+1, err)
+		}
+	}
+	for i, sequence := range a.Sequences {
+		if err := validateKeys(sequence.Keys, 2); err != nil {
+			return fmt.Errorf("последовательность %d: %w", i+1, err)
+		}
+		if err := check(sequence.Command); err != nil {
+			return fmt.Errorf("последовательность %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateButtons(buttons []Button, check func(actions.Action) error) error {
+	for i := range buttons {
+		if err := validateButton(&buttons[i], check); err != nil {
+			if buttons[i].Name != "" {
+				return fmt.Errorf("кнопка %d (%s): %w", i+1, buttons[i].Name, err)
+			}
+			return fmt.Errorf("кнопка %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateButton(button *Button, check func(actions.Action) error) error {
+	if err := check(button.Command); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(button.Gestures))
+	for name := range button.Gestures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Аккорды и последовательности задаются в профиле, а не на кнопке
+		gesture, ok := gestures.ParseGesture(name)
+		if !ok || gesture == gestures.ChordPress || gesture == gestures.SequencePress {
+			return fmt.Errorf("неизвестный жест %q", name)
+		}
+		if err := check(button.Gestures[name]); err != nil {
+			return fmt.Errorf("жест %s: %w", name, err)
+		}
+	}
+	if button.Text != "" {
+		if _, err := labeltemplate.Parse(button.Text); err != nil {
+			return err
+		}
+	}
+	for i, state := range button.States {
+		if err := check(state.Command); err != nil {
+			return fmt.Errorf("состояние %d: %w", i+1, err)
+		}
+	}
+	return validateButtons(button.Buttons, check)
+}
+
+// validateKeys проверяет номера кнопок аккорда или последовательности:
+// их не меньше minKeys и все они есть на пульте
+func validateKeys(keys []int, minKeys int) error {
+	if len(keys) < minKeys {
+		return fmt.Errorf("нужно кнопок: не меньше %d, задано %d", minKeys, len(keys))
+	}
+	for _, key := range keys {
+		if key < 0 || key >= ulanzid200.ButtonCount {
+			return fmt.Errorf("кнопка %d вне диапазона 0..%d", key, ulanzid200.ButtonCount-1)
+		}
+	}
+	return nil
+}
+
+// validateEnvironment проверяет env и cwd профиля
+func (a *Application) validateEnvironment() error {
+	for name := range a.Env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("env: недопустимое имя переменной %q", name)
+		}
+	}
+	if a.Cwd != "" && !filepath.IsAbs(expandPath(a.Cwd)) {
+		return fmt.Errorf("cwd: ожидается абсолютный путь или путь от ~, получено %q", a.Cwd)
+	}
+	return nil
+}
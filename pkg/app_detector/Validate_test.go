@@ -0,0 +1,67 @@
+package appdetector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSettingsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		// Часть текста ошибки; пустая строка — настройки корректны
+		err string
+	}{
+		{"корректный профиль", `{"default": {
+			"buttons": [{"text": "{{.cpu}}%", "gestures": {"long": {"type": "page", "page": "next"}, "double": {"type": "shell", "command": "true"}}}],
+			"chords": [{"keys": [0, 12], "command": {"type": "page", "page": "home"}}],
+			"sequences": [{"keys": [3, 4], "command": {"type": "page", "page": "home"}}]
+		}}`, ""},
+		{"неизвестный жест", `{"default": {"buttons": [{"gestures": {"triple": {"type": "page", "page": "next"}}}]}}`,
+			`неизвестный жест "triple"`},
+		{"аккорд как жест кнопки", `{"default": {"buttons": [{"gestures": {"chord": {"type": "page", "page": "next"}}}]}}`,
+			`неизвестный жест "chord"`},
+		{"жест в папке", `{"default": {"buttons": [{"buttons": [{"gestures": {"hold": {"type": "page", "page": "next"}}}]}]}}`,
+			`неизвестный жест "hold"`},
+		{"неверный шаблон", `{"default": {"buttons": [{"text": "{{.cpu"}]}}`,
+			"ошибка в шаблоне подписи"},
+		{"кнопка аккорда за пределами пульта", `{"default": {"buttons": [],
+			"chords": [{"keys": [0, 13], "command": {"type": "page", "page": "home"}}]}}`,
+			"аккорд 1: кнопка 13 вне диапазона 0..12"},
+		{"отрицательная кнопка", `{"default": {"buttons": [],
+			"sequences": [{"keys": [1, -1], "command": {"type": "page", "page": "home"}}]}}`,
+			"последовательность 1: кнопка -1 вне диапазона"},
+		{"аккорд из одной кнопки", `{"default": {"buttons": [],
+			"chords": [{"keys": [4], "command": {"type": "page", "page": "home"}}]}}`,
+			"аккорд 1: нужно кнопок: не меньше 2"},
+		{"on_error ссылается на несуществующий профиль", `{"default": {"buttons": [{"command": {"type": "shell", "command": "make",
+			"on_error": {"type": "switch_profile", "profile": "missing"}}}]}}`,
+			`on_error: профиль "missing" не найден`},
+		{"последовательность из одной кнопки", `{"default": {"buttons": [],
+			"sequences": [{"keys": [5], "command": {"type": "page", "page": "home"}}]}}`,
+			"последовательность 1: нужно кнопок: не меньше 2"},
+		{"пустая последовательность", `{"default": {"buttons": [],
+			"sequences": [{"keys": [], "command": {"type": "page", "page": "home"}}]}}`,
+			"последовательность 1: нужно кнопок"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseSettings([]byte(tt.settings))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("ошибка %v, ожидалось %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDefaultSettingsValid(t *testing.T) {
+	if _, _, err := parseSettings(defaultSettings); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package gestures
+
+import "time"
+
+// Chord — несколько кнопок, зажатых одновременно
+type Chord struct {
+	Keys []int
+}
+
+// Sequence — кнопки, нажатые по порядку; каждая не позже Within после предыдущей.
+// Отдельные нажатия кнопок последовательности при этом тоже срабатывают.
+type Sequence struct {
+	Keys   []int
+	Within time.Duration
+}
+
+const DefaultSequenceWithin = 500 * time.Millisecond
+
+type press struct {
+	index int
+	at    time.Time
+}
+
+// SetChords задаёт аккорды; номер аккорда приходит в Event.Binding
+func (d *Detector) SetChords(chords []Chord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.chords = chords
+}
+
+// SetSequences задаёт последовательности; номер приходит в Event.Binding
+func (d *Detector) SetSequences(sequences []Sequence) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sequences = sequences
+	d.history = nil
+}
+
+// matchChords вызывается при нажатии кнопки index под блокировкой
+func (d *Detector) matchChords(index int, at time.Time) []*Event {
+	var events []*Event
+	for binding, chord := range d.chords {
+		if len(chord.Keys) < 2 || !containsKey(chord.Keys, index) {
+			continue
+		}
+		complete := true
+		for _, k := range chord.Keys {
+			if key, ok := d.keys[k]; !ok || !key.down || key.consumed {
+				complete = false
+				break
+			}
+		}
+		if !complete {
+			continue
+		}
+		for _, k := range chord.Keys {
+			key := d.keys[k]
+			key.consumed = true
+			if key.holdTimer != nil {
+				key.holdTimer.Stop()
+				key.holdTimer = nil
+			}
+		}
+		events = append(events, &Event{Index: index, Gesture: ChordPress, Time: at, Binding: binding})
+	}
+	return events
+}
+
+// matchSequences вызывается при нажатии кнопки index под блокировкой
+func (d *Detector) matchSequences(index int, at time.Time) []*Event {
+	if len(d.sequences) == 0 {
+		return nil
+	}
+	maxLen := 0
+	for _, sequence := range d.sequences {
+		maxLen = max(maxLen, len(sequence.Keys))
+	}
+	d.history = append(d.history, press{index: index, at: at})
+	if len(d.history) > maxLen {
+		d.history = d.history[len(d.history)-maxLen:]
+	}
+
+	var events []*Event
+	for binding, sequence := range d.sequences {
+		if d.matchSequence(sequence) {
+			events = append(events, &Event{Index: index, Gesture: SequencePress, Time: at, Binding: binding})
+		}
+	}
+	if len(events) > 0 {
+		d.history = nil
+	}
+	return events
+}
+
+func (d *Detector) matchSequence(sequence Sequence) bool {
+	n := len(sequence.Keys)
+	if n < 2 || len(d.history) < n {
+		return false
+	}
+	within := sequence.Within
+	if within <= 0 {
+		within = DefaultSequenceWithin
+	}
+	tail := d.history[len(d.history)-n:]
+	for i, p := range tail {
+		if p.index != sequence.Keys[i] {
+			return false
+		}
+		if i > 0 && p.at.Sub(tail[i-1].at) > within {
+			return false
+		}
+	}
+	return true
+}
+
+func containsKey(keys []int, index int) bool {
+	for _, k := range keys {
+		if k == index {
+			return true
+		}
+	}
+	return false
+}
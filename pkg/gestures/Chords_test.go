@@ -0,0 +1,78 @@
+package gestures
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChordsAndSequences(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    KeyConfig
+		chords    []Chord
+		sequences []Sequence
+		steps     []step
+		want      []string
+	}{
+		{"аккорд", KeyConfig{},
+			[]Chord{{Keys: []int{0, 1}}}, nil,
+			[]step{p(0, 0), p(50, 1), r(100, 0), r(120, 1)},
+			[]string{"50: 1 chord #0"}},
+		{"аккорд гасит долгое нажатие своих кнопок", KeyConfig{LongPress: 500 * time.Millisecond},
+			[]Chord{{Keys: []int{0, 1}}}, nil,
+			[]step{p(0, 0), p(50, 1), r(900, 0), r(900, 1)},
+			[]string{"50: 1 chord #0"}},
+		{"кнопки по очереди — не аккорд", KeyConfig{},
+			[]Chord{{Keys: []int{0, 1}}}, nil,
+			[]step{p(0, 0), r(50, 0), p(100, 1), r(150, 1)},
+			[]string{"50: 0 short", "150: 1 short"}},
+		{"кнопка аккорда не входит во второй аккорд", KeyConfig{},
+			[]Chord{{Keys: []int{0, 1}}, {Keys: []int{0, 2}}}, nil,
+			[]step{p(0, 0), p(50, 1), p(100, 2), r(200, 0), r(200, 1), r(200, 2)},
+			[]string{"50: 1 chord #0", "200: 2 short"}},
+		{"аккорд из трёх кнопок", KeyConfig{},
+			[]Chord{{Keys: []int{0, 1, 2}}}, nil,
+			[]step{p(0, 2), p(10, 0), p(20, 1), r(100, 0), r(100, 1), r(100, 2)},
+			[]string{"20: 1 chord #0"}},
+		{"последовательность в пределах окна", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1}, Within: 300 * time.Millisecond}},
+			[]step{p(0, 0), r(50, 0), p(300, 1), r(350, 1)},
+			[]string{"50: 0 short", "300: 1 sequence #0", "350: 1 short"}},
+		{"последовательность за пределами окна", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1}, Within: 300 * time.Millisecond}},
+			[]step{p(0, 0), r(50, 0), p(301, 1), r(350, 1)},
+			[]string{"50: 0 short", "350: 1 short"}},
+		{"окно по умолчанию", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1}}},
+			[]step{p(0, 0), r(10, 0), p(500, 1), r(510, 1), p(1011, 0), r(1020, 0)},
+			[]string{"10: 0 short", "500: 1 sequence #0", "510: 1 short", "1020: 0 short"}},
+		{"лишние нажатия в начале истории", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1}}},
+			[]step{p(0, 0), r(10, 0), p(20, 0), r(30, 0), p(40, 0), r(50, 0), p(60, 1), r(70, 1)},
+			[]string{"10: 0 short", "30: 0 short", "50: 0 short", "60: 1 sequence #0", "70: 1 short"}},
+		{"история очищается после срабатывания", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 0}}},
+			[]step{p(0, 0), r(10, 0), p(20, 0), r(30, 0), p(40, 0), r(50, 0), p(60, 0), r(70, 0)},
+			[]string{"10: 0 short", "20: 0 sequence #0", "30: 0 short", "50: 0 short",
+				"60: 0 sequence #0", "70: 0 short"}},
+		{"вложенные последовательности срабатывают вместе", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1, 2}}, {Keys: []int{1, 2}}},
+			[]step{p(0, 0), r(10, 0), p(20, 1), r(30, 1), p(40, 2), r(50, 2)},
+			[]string{"10: 0 short", "30: 1 short", "40: 2 sequence #0", "40: 2 sequence #1", "50: 2 short"}},
+		{"нажатие не делится между последовательностями", KeyConfig{},
+			nil, []Sequence{{Keys: []int{0, 1}}, {Keys: []int{1, 2}}},
+			[]step{p(0, 0), r(10, 0), p(20, 1), r(30, 1), p(40, 2), r(50, 2)},
+			[]string{"10: 0 short", "20: 1 sequence #0", "30: 1 short", "50: 2 short"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, clock := newTestDetector(tt.config)
+			d.SetChords(tt.chords)
+			d.SetSequences(tt.sequences)
+			if got := play(d, clock, tt.steps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("жесты %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// Package gestures превращает сырые нажатия и отпускания кнопок в жесты:
+// короткое и долгое нажатие, двойное нажатие и автоповтор при удержании.
+package gestures
+
+import (
+	"sync"
+	"time"
+)
+
+type Gesture int
+
+const (
+	Down Gesture = iota
+	Up
+	ShortPress
+	LongPress
+	LongRelease
+	DoublePress
+	HoldRepeat
+	ChordPress
+	SequencePress
+)
+
+var gestureNames = map[Gesture]string{
+	Down:          "down",
+	Up:            "up",
+	ShortPress:    "short",
+	LongPress:     "long",
+	LongRelease:   "long_up",
+	DoublePress:   "double",
+	HoldRepeat:    "repeat",
+	ChordPress:    "chord",
+	SequencePress: "sequence",
+}
+
+func (g Gesture) String() string {
+	return gestureNames[g]
+}
+
+// ParseGesture возвращает жест по имени, используемому в settings.json
+func ParseGesture(name string) (Gesture, bool) {
+	for g, n := range gestureNames {
+		if n == name {
+			return g, true
+		}
+	}
+	return 0, false
+}
+
+// Event — распознанный жест
+type Event struct {
+	Index   int
+	Gesture Gesture
+	Time    time.Time
+	// Сколько кнопка была нажата (для Up, LongRelease и ShortPress)
+	Duration time.Duration
+	// Номер повтора для HoldRepeat, начиная с 1
+	Count int
+	// Номер аккорда или последовательности для ChordPress и SequencePress
+	Binding int
+}
+
+// KeyConfig — пороги распознавания для кнопки. Нулевой LongPress отключает
+// долгое нажатие и автоповтор: отпускание после любого удержания даёт
+// ShortPress. Нулевые DoublePress и Repeat отключают двойное нажатие
+// и автоповтор: тогда короткое нажатие приходит сразу после отпускания.
+type KeyConfig struct {
+	LongPress   time.Duration
+	DoublePress time.Duration
+	Repeat      time.Duration
+}
+
+// timer — запущенный таймер; в тестах подменяется вместе с часами
+type timer interface {
+	Stop() bool
+}
+
+type keyState struct {
+	down      bool
+	downAt    time.Time
+	longFired bool
+	// Кнопка стала частью аккорда: короткое и долгое нажатие не срабатывают
+	consumed   bool
+	repeats    int
+	holdTimer  timer
+	shortTimer timer
+	// Отпускание, ожидающее второго нажатия
+	pendingShort *Event
+	// Поколение нажатия, чтобы игнорировать сработавшие устаревшие таймеры
+	generation int
+}
+
+type Detector struct {
+	mu        sync.Mutex
+	defaults  KeyConfig
+	configs   map[int]KeyConfig
+	keys      map[int]*keyState
+	chords    []Chord
+	sequences []Sequence
+	history   []press
+	events    chan *Event
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) timer
+}
+
+// NewDetector создаёт распознаватель; defaults — пороги кнопок,
+// для которых не вызывался SetKeyConfig
+func NewDetector(defaults KeyConfig) *Detector {
+	return &Detector{
+		defaults: defaults,
+		configs:  make(map[int]KeyConfig),
+		keys:     make(map[int]*keyState),
+		events:   make(chan *Event, 64),
+		now:      time.Now,
+		afterFunc: func(delay time.Duration, f func()) timer {
+			return time.AfterFunc(delay, f)
+		},
+	}
+}
+
+func (d *Detector) Events() chan *Event {
+	return d.events
+}
+
+// SetKeyConfig задаёт пороги для кнопки вместо порогов по умолчанию
+func (d *Detector) SetKeyConfig(index int, config KeyConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.configs[index] = config
+}
+
+// ResetKeyConfigs возвращает пороги по умолчанию для всех кнопок
+func (d *Detector) ResetKeyConfigs() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.configs = make(map[int]KeyConfig)
+}
+
+func (d *Detector) config(index int) KeyConfig {
+	if config, ok := d.configs[index]; ok {
+		return config
+	}
+	return d.defaults
+}
+
+func (d *Detector) key(index int) *keyState {
+	key, ok := d.keys[index]
+	if !ok {
+		key = &keyState{}
+		d.keys[index] = key
+	}
+	return key
+}
+
+// Feed принимает сырое событие кнопки
+func (d *Detector) Feed(index int, pressed bool, at time.Time) {
+	if at.IsZero() {
+		at = d.now()
+	}
+	d.mu.Lock()
+	var events []*Event
+	if pressed {
+		events = d.press(index, at)
+	} else {
+		events = d.release(index, at)
+	}
+	d.mu.Unlock()
+	d.emit(events)
+}
+
+func (d *Detector) press(index int, at time.Time) []*Event {
+	key := d.key(index)
+	if key.down {
+		return nil
+	}
+	config := d.config(index)
+	key.down = true
+	key.downAt = at
+	key.longFired = false
+	key.consumed = false
+	key.repeats = 0
+	key.generation++
+
+	if config.LongPress > 0 {
+		generation := key.generation
+		key.holdTimer = d.afterFunc(config.LongPress, func() {
+			d.hold(index, generation)
+		})
+	}
+	events := []*Event{{Index: index, Gesture: Down, Time: at}}
+	events = append(events, d.matchChords(index, at)...)
+	events = append(events, d.matchSequences(index, at)...)
+	return events
+}
+
+func (d *Detector) release(index int, at time.Time) []*Event {
+	key := d.key(index)
+	if !key.down {
+		return nil
+	}
+	config := d.config(index)
+	key.down = false
+	key.generation++
+	if key.holdTimer != nil {
+		key.holdTimer.Stop()
+		key.holdTimer = nil
+	}
+
+	duration := at.Sub(key.downAt)
+	events := []*Event{{Index: index, Gesture: Up, Time: at, Duration: duration}}
+	if key.consumed {
+		return events
+	}
+	if key.longFired {
+		return append(events, &Event{Index: index, Gesture: LongRelease, Time: at, Duration: duration})
+	}
+
+	short := &Event{Index: index, Gesture: ShortPress, Time: at, Duration: duration}
+	if key.pendingShort != nil {
+		key.shortTimer.Stop()
+		key.shortTimer = nil
+		key.pendingShort = nil
+		return append(events, &Event{Index: index, Gesture: DoublePress, Time: at, Duration: duration})
+	}
+	if config.DoublePress <= 0 {
+		return append(events, short)
+	}
+
+	key.pendingShort = short
+	key.shortTimer = d.afterFunc(config.DoublePress, func() {
+		d.mu.Lock()
+		pending := key.pendingShort
+		key.pendingShort = nil
+		key.shortTimer = nil
+		d.mu.Unlock()
+		if pending != nil {
+			d.emit([]*Event{pending})
+		}
+	})
+	return events
+}
+
+// hold срабатывает по таймеру удержания: долгое нажатие, затем автоповтор
+func (d *Detector) hold(index int, generation int) {
+	d.mu.Lock()
+	key := d.key(index)
+	if !key.down || key.generation != generation {
+		d.mu.Unlock()
+		return
+	}
+	config := d.config(index)
+	at := d.now()
+	var events []*Event
+	if !key.longFired {
+		key.longFired = true
+		// Второе нажатие оказалось долгим: первое остаётся коротким
+		if key.pendingShort != nil {
+			key.shortTimer.Stop()
+			events = append(events, key.pendingShort)
+			key.shortTimer = nil
+			key.pendingShort = nil
+		}
+		events = append(events, &Event{Index: index, Gesture: LongPress, Time: at, Duration: at.Sub(key.downAt)})
+	} else {
+		key.repeats++
+		events = append(events, &Event{Index: index, Gesture: HoldRepeat, Time: at, Duration: at.Sub(key.downAt), Count: key.repeats})
+	}
+	if config.Repeat > 0 {
+		key.holdTimer = d.afterFunc(config.Repeat, func() {
+			d.hold(index, generation)
+		})
+	}
+	d.mu.Unlock()
+	d.emit(events)
+}
+
+func (d *Detector) emit(events []*Event) {
+	for _, event := range events {
+		d.events <- event
+	}
+}
@@ -0,0 +1,181 @@
+package gestures
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock — часы и таймеры, которые двигает сам тест
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	active := !t.stopped
+	t.stopped = true
+	return active
+}
+
+func (c *fakeClock) afterFunc(delay time.Duration, f func()) timer {
+	t := &fakeTimer{at: c.now.Add(delay), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// advance переводит часы на to, по порядку запуская таймеры, срок которых наступил
+func (c *fakeClock) advance(to time.Time) {
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.stopped && !t.at.After(to) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.stopped = true
+		c.now = next.at
+		next.f()
+	}
+	c.now = to
+}
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestDetector(defaults KeyConfig) (*Detector, *fakeClock) {
+	clock := &fakeClock{now: testStart}
+	d := NewDetector(defaults)
+	d.now = func() time.Time { return clock.now }
+	d.afterFunc = clock.afterFunc
+	return d, clock
+}
+
+// step — нажатие или отпускание кнопки index через ms после начала теста
+type step struct {
+	ms      int
+	index   int
+	pressed bool
+}
+
+func p(ms, index int) step { return step{ms, index, true} }
+func r(ms, index int) step { return step{ms, index, false} }
+
+// play подаёт шаги в детектор, дожидается всех таймеров и возвращает жесты
+// в виде "мс: кнопка жест"; down и up пропускаются
+func play(d *Detector, clock *fakeClock, steps []step) []string {
+	var got []string
+	collect := func() {
+		for {
+			select {
+			case event := <-d.Events():
+				if event.Gesture == Down || event.Gesture == Up {
+					continue
+				}
+				s := fmt.Sprintf("%d: %d %v", event.Time.Sub(testStart).Milliseconds(), event.Index, event.Gesture)
+				switch event.Gesture {
+				case HoldRepeat:
+					s += fmt.Sprintf(" %d", event.Count)
+				case ChordPress, SequencePress:
+					s += fmt.Sprintf(" #%d", event.Binding)
+				}
+				got = append(got, s)
+			default:
+				return
+			}
+		}
+	}
+	for _, st := range steps {
+		clock.advance(testStart.Add(time.Duration(st.ms) * time.Millisecond))
+		collect()
+		d.Feed(st.index, st.pressed, clock.now)
+		collect()
+	}
+	clock.advance(clock.now.Add(time.Minute))
+	collect()
+	return got
+}
+
+func TestDetector(t *testing.T) {
+	const (
+		long   = 500 * time.Millisecond
+		double = 300 * time.Millisecond
+		repeat = 200 * time.Millisecond
+	)
+	tests := []struct {
+		name   string
+		config KeyConfig
+		steps  []step
+		want   []string
+	}{
+		{"короткое нажатие", KeyConfig{},
+			[]step{p(0, 0), r(100, 0)},
+			[]string{"100: 0 short"}},
+		{"удержание без долгого жеста остаётся коротким", KeyConfig{},
+			[]step{p(0, 0), r(700, 0)},
+			[]string{"700: 0 short"}},
+		{"долгое нажатие", KeyConfig{LongPress: long},
+			[]step{p(0, 0), r(700, 0)},
+			[]string{"500: 0 long", "700: 0 long_up"}},
+		{"отпускание до порога долгого нажатия", KeyConfig{LongPress: long},
+			[]step{p(0, 0), r(499, 0)},
+			[]string{"499: 0 short"}},
+		{"двойное нажатие", KeyConfig{DoublePress: double},
+			[]step{p(0, 0), r(50, 0), p(150, 0), r(200, 0)},
+			[]string{"200: 0 double"}},
+		{"короткое после ожидания второго нажатия", KeyConfig{DoublePress: double},
+			[]step{p(0, 0), r(50, 0), p(400, 0), r(450, 0)},
+			[]string{"50: 0 short", "450: 0 short"}},
+		{"второе нажатие оказалось долгим", KeyConfig{LongPress: long, DoublePress: double},
+			[]step{p(0, 0), r(50, 0), p(150, 0), r(900, 0)},
+			[]string{"50: 0 short", "650: 0 long", "900: 0 long_up"}},
+		{"автоповтор", KeyConfig{LongPress: long, Repeat: repeat},
+			[]step{p(0, 0), r(950, 0)},
+			[]string{"500: 0 long", "700: 0 repeat 1", "900: 0 repeat 2", "950: 0 long_up"}},
+		{"повторное нажатие сбрасывает повторы", KeyConfig{LongPress: long, Repeat: repeat},
+			[]step{p(0, 0), r(750, 0), p(800, 0), r(1400, 0)},
+			[]string{"500: 0 long", "700: 0 repeat 1", "750: 0 long_up",
+				"1300: 0 long", "1400: 0 long_up"}},
+		{"кнопки независимы", KeyConfig{LongPress: long},
+			[]step{p(0, 0), p(100, 1), r(200, 1), r(700, 0)},
+			[]string{"200: 1 short", "500: 0 long", "700: 0 long_up"}},
+		{"повторное нажатие без отпускания игнорируется", KeyConfig{},
+			[]step{p(0, 0), p(50, 0), r(100, 0), r(150, 0)},
+			[]string{"100: 0 short"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, clock := newTestDetector(tt.config)
+			if got := play(d, clock, tt.steps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("жесты %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectorKeyConfig(t *testing.T) {
+	d, clock := newTestDetector(KeyConfig{LongPress: 500 * time.Millisecond})
+	// Кнопке без долгого жеста порог по умолчанию не достаётся
+	d.SetKeyConfig(1, KeyConfig{})
+	got := play(d, clock, []step{p(0, 0), p(0, 1), r(700, 0), r(700, 1)})
+	want := []string{"500: 0 long", "700: 0 long_up", "700: 1 short"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("жесты %q, ожидалось %q", got, want)
+	}
+
+	d.ResetKeyConfigs()
+	got = play(d, clock, []step{p(61000, 1), r(61700, 1)})
+	want = []string{"61500: 1 long", "61700: 1 long_up"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("после сброса: жесты %q, ожидалось %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package icons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Frame — кадр анимации: готовая иконка и время показа
+type Frame struct {
+	Path  string
+	Delay time.Duration
+}
+
+// Задержка для кадров GIF без задержки, как в браузерах
+const DefaultFrameDelay = 100 * time.Millisecond
+
+var (
+	gifFramesMu sync.Mutex
+	gifFrames   = map[string][]Frame{}
+)
+
+// ExtractGIF раскладывает анимированный GIF на кадры размером Size x Size.
+// Кадры собираются с учётом способа очистки GIF и кэшируются в cacheDir
+// под хэшем файла.
+func ExtractGIF(path, cacheDir string) ([]Frame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])[:32]
+
+	gifFramesMu.Lock()
+	defer gifFramesMu.Unlock()
+	if frames, ok := gifFrames[key]; ok {
+		return frames, nil
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать анимацию %s: %w", path, err)
+	}
+	bounds := image.Rect(0, 0, anim.Config.Width, anim.Config.Height)
+	if bounds.Empty() && len(anim.Image) > 0 {
+		bounds = anim.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	frames := make([]Frame, 0, len(anim.Image))
+	for i, img := range anim.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(anim.Disposal) {
+			disposal = anim.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+
+		framePath := filepath.Join(cacheDir, fmt.Sprintf("%s-%d.png", key, i))
+		if _, err := os.Stat(framePath); err != nil {
+			if err := writePNG(framePath, Fit(canvas)); err != nil {
+				return nil, err
+			}
+		}
+		delay := DefaultFrameDelay
+		if i < len(anim.Delay) && anim.Delay[i] > 1 {
+			delay = time.Duration(anim.Delay[i]) * 10 * time.Millisecond
+		}
+		frames = append(frames, Frame{Path: framePath, Delay: delay})
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("в анимации %s нет кадров", path)
+	}
+	gifFrames[key] = frames
+	return frames, nil
+}
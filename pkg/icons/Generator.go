@@ -0,0 +1,366 @@
+package icons
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Меняется, когда меняется способ рисования, чтобы не брать старые иконки из кэша
+const generatorVersion = 1
+
+// Generator рисует иконки по Spec и складывает их в кэш
+type Generator struct {
+	iconsDir string
+	cacheDir string
+
+	mu    sync.Mutex
+	fonts map[string]*opentype.Font
+	faces map[string]font.Face
+}
+
+// NewGenerator создаёт генератор. Картинки ищутся в iconsDir,
+// готовые иконки кладутся в cacheDir.
+func NewGenerator(iconsDir, cacheDir string) *Generator {
+	return &Generator{
+		iconsDir: iconsDir,
+		cacheDir: cacheDir,
+		fonts:    make(map[string]*opentype.Font),
+		faces:    make(map[string]font.Face),
+	}
+}
+
+// Generate возвращает путь к PNG для spec, рисуя иконку, только если её нет в кэше.
+// Имя файла — хэш спецификации и картинки, поэтому изменение любой из них даёт новый файл.
+func (g *Generator) Generate(spec Spec) (string, error) {
+	key, err := g.cacheKey(spec)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(g.cacheDir, key+".png")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	img, err := g.Render(spec)
+	if err != nil {
+		return "", err
+	}
+	if err := writePNG(path, img); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (g *Generator) cacheKey(spec Spec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d\n", generatorVersion)
+	hash.Write(data)
+	if spec.Image != "" && g.imagePath(spec.Image) == "" {
+		return "", fmt.Errorf("картинка %q не найдена", spec.Image)
+	}
+	for _, path := range []string{g.imagePath(spec.Image), g.fontPath(spec.Font)} {
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:32], nil
+}
+
+// Render рисует иконку размером Size x Size
+func (g *Generator) Render(spec Spec) (*image.RGBA, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, Size, Size))
+	drawBackground(img, spec)
+
+	if spec.Image != "" {
+		if err := g.drawImage(img, spec); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Text != "" {
+		if err := g.drawText(img, spec); err != nil {
+			return nil, err
+		}
+	}
+	for _, badge := range spec.Badges {
+		if err := g.drawBadge(img, badge); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+func drawBackground(img *image.RGBA, spec Spec) {
+	if len(spec.Gradient) < 2 {
+		draw.Draw(img, img.Bounds(), image.NewUniform(colorOr(spec.Background, DefaultBackground)), image.Point{}, draw.Src)
+		return
+	}
+	stops := make([]color.RGBA, len(spec.Gradient))
+	for i, c := range spec.Gradient {
+		stops[i] = colorOr(c, DefaultBackground)
+	}
+	for y := 0; y < Size; y++ {
+		for x := 0; x < Size; x++ {
+			var t float64
+			switch spec.GradientDirection {
+			case "horizontal":
+				t = float64(x) / (Size - 1)
+			case "diagonal":
+				t = float64(x+y) / (2 * (Size - 1))
+			default:
+				t = float64(y) / (Size - 1)
+			}
+			img.SetRGBA(x, y, gradientAt(stops, t))
+		}
+	}
+}
+
+// gradientAt возвращает цвет в точке t от 0 до 1 между равномерно расставленными цветами
+func gradientAt(stops []color.RGBA, t float64) color.RGBA {
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f := pos - float64(i)
+	a, b := stops[i], stops[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+func (g *Generator) imagePath(name string) string {
+	if IsSystemIcon(name) {
+		path, _ := ResolveIcon(name)
+		return path
+	}
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(g.iconsDir, name)
+}
+
+// drawImage вписывает картинку по центру с сохранением пропорций.
+// Поддерживаются те же форматы, что и в Normalize.
+func (g *Generator) drawImage(img *image.RGBA, spec Spec) error {
+	path := g.imagePath(spec.Image)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	src, err := Decode(data, path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать картинку %s: %w", spec.Image, err)
+	}
+
+	scale := spec.ImageScale
+	if scale <= 0 || scale > 1 {
+		scale = 1
+	}
+	box := int(Size * scale)
+	x := (Size - box) / 2
+	if box == Size {
+		draw.Draw(img, img.Bounds(), src, image.Point{}, draw.Over)
+		return nil
+	}
+	xdraw.CatmullRom.Scale(img, image.Rect(x, x, x+box, x+box), src, src.Bounds(), draw.Over, nil)
+	return nil
+}
+
+// drawText рисует строки текста по центру, уменьшая шрифт, пока самая
+// длинная строка не поместится по ширине
+func (g *Generator) drawText(img *image.RGBA, spec Spec) error {
+	size := spec.FontSize
+	if size <= 0 {
+		size = DefaultFontSize
+	}
+	lines := strings.Split(spec.Text, "\n")
+	padding := Size / 16
+
+	var face font.Face
+	for {
+		var err error
+		face, err = g.face(spec.Font, size)
+		if err != nil {
+			return err
+		}
+		if widest(face, lines) <= Size-2*padding || size <= 8 {
+			break
+		}
+		size *= 0.9
+	}
+
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	ascent := metrics.Ascent.Ceil()
+	height := lineHeight * len(lines)
+	var top int
+	switch spec.Align {
+	case "top":
+		top = padding
+	case "bottom":
+		top = Size - padding - height
+	default:
+		top = (Size - height) / 2
+	}
+
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(colorOr(spec.Color, DefaultColor)), Face: face}
+	for i, line := range lines {
+		width := drawer.MeasureString(line).Ceil()
+		drawer.Dot = fixed.P((Size-width)/2, top+i*lineHeight+ascent)
+		drawer.DrawString(line)
+	}
+	return nil
+}
+
+func widest(face font.Face, lines []string) int {
+	result := 0
+	for _, line := range lines {
+		if width := font.MeasureString(face, line).Ceil(); width > result {
+			result = width
+		}
+	}
+	return result
+}
+
+// Размер значка относительно иконки
+const badgeRadius = Size / 7
+
+func (g *Generator) drawBadge(img *image.RGBA, badge Badge) error {
+	margin := Size / 28
+	cx, cy := Size-margin-badgeRadius, margin+badgeRadius
+	switch badge.Position {
+	case "top-left":
+		cx = margin + badgeRadius
+	case "bottom-left":
+		cx, cy = margin+badgeRadius, Size-margin-badgeRadius
+	case "bottom-right":
+		cy = Size - margin - badgeRadius
+	}
+
+	fill := image.NewUniform(colorOr(badge.Background, DefaultBadgeColor))
+	for y := cy - badgeRadius; y <= cy+badgeRadius; y++ {
+		for x := cx - badgeRadius; x <= cx+badgeRadius; x++ {
+			dx, dy := x-cx, y-cy
+			if dx*dx+dy*dy <= badgeRadius*badgeRadius {
+				img.Set(x, y, fill.C)
+			}
+		}
+	}
+	if badge.Text == "" {
+		return nil
+	}
+
+	size := float64(badgeRadius)
+	var face font.Face
+	for {
+		var err error
+		face, err = g.face("bold", size)
+		if err != nil {
+			return err
+		}
+		if font.MeasureString(face, badge.Text).Ceil() <= 2*badgeRadius-4 || size <= 8 {
+			break
+		}
+		size *= 0.9
+	}
+	metrics := face.Metrics()
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(colorOr(badge.Color, DefaultColor)), Face: face}
+	width := drawer.MeasureString(badge.Text).Ceil()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	drawer.Dot = fixed.P(cx-width/2, cy+(ascent-descent)/2)
+	drawer.DrawString(badge.Text)
+	return nil
+}
+
+func (g *Generator) fontPath(name string) string {
+	switch name {
+	case "", "regular", "bold", "mono":
+		return ""
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(g.iconsDir, name)
+}
+
+func (g *Generator) face(name string, size float64) (font.Face, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%.2f", name, size)
+	if face, ok := g.faces[key]; ok {
+		return face, nil
+	}
+	f, ok := g.fonts[name]
+	if !ok {
+		var data []byte
+		switch name {
+		case "", "regular":
+			data = goregular.TTF
+		case "bold":
+			data = gobold.TTF
+		case "mono":
+			data = gomono.TTF
+		default:
+			var err error
+			data, err = os.ReadFile(g.fontPath(name))
+			if err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		f, err = opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать шрифт %s: %w", name, err)
+		}
+		g.fonts[name] = f
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	g.faces[key] = face
+	return face, nil
+}
+
+func colorOr(value, fallback string) color.RGBA {
+	if value != "" {
+		if c, err := ParseColor(value); err == nil {
+			return c
+		}
+	}
+	c, _ := ParseColor(fallback)
+	return c
+}
@@ -0,0 +1,147 @@
+package icons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var errBadICO = errors.New("неверный формат ICO")
+
+// decodeICO выбирает в ICO самую большую картинку и декодирует её.
+// Внутри ICO бывают PNG и DIB (BMP без заголовка файла) с 1, 4, 8, 24 и 32 битами на пиксель.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[0:]) != 0 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errBadICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errBadICO
+	}
+
+	best, bestArea := -1, 0
+	for i := 0; i < count; i++ {
+		entry := data[6+16*i:]
+		w, h := int(entry[0]), int(entry[1])
+		// 0 в размере означает 256
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		if w*h > bestArea {
+			best, bestArea = i, w*h
+		}
+	}
+	entry := data[6+16*best:]
+	size := int(binary.LittleEndian.Uint32(entry[8:]))
+	offset := int(binary.LittleEndian.Uint32(entry[12:]))
+	if offset < 0 || size <= 0 || offset+size > len(data) {
+		return nil, errBadICO
+	}
+	payload := data[offset : offset+size]
+
+	if bytes.HasPrefix(payload, []byte("\x89PNG")) {
+		return png.Decode(bytes.NewReader(payload))
+	}
+	return decodeDIB(payload)
+}
+
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errBadICO
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	// Высота в ICO удвоена: после цветов идёт маска прозрачности
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colors := int(binary.LittleEndian.Uint32(data[32:]))
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 || headerSize < 40 || headerSize > len(data) {
+		return nil, errBadICO
+	}
+	if compression != 0 {
+		return nil, fmt.Errorf("сжатые DIB в ICO не поддерживаются")
+	}
+
+	pos := headerSize
+	var palette []color.NRGBA
+	if bpp <= 8 {
+		if colors == 0 {
+			colors = 1 << bpp
+		}
+		if pos+4*colors > len(data) {
+			return nil, errBadICO
+		}
+		palette = make([]color.NRGBA, colors)
+		for i := range palette {
+			p := data[pos+4*i:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+		}
+		pos += 4 * colors
+	}
+
+	stride := (width*bpp + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if pos+stride*height > len(data) {
+		return nil, errBadICO
+	}
+	pixels := data[pos : pos+stride*height]
+	var mask []byte
+	if maskEnd := pos + stride*height + maskStride*height; maskEnd <= len(data) {
+		mask = data[pos+stride*height : maskEnd]
+	}
+
+	// В старых 32-битных иконках альфа-канал пуст, прозрачность задаёт маска
+	hasAlpha := false
+	if bpp == 32 {
+		for i := 3; i < len(pixels); i += 4 {
+			if pixels[i] != 0 {
+				hasAlpha = true
+				break
+			}
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		// Строки DIB идут снизу вверх
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				p := row[4*x:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+				if !hasAlpha {
+					c.A = 0xff
+				}
+			case 24:
+				p := row[3*x:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			case 1, 4, 8:
+				bit := x * bpp
+				index := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			default:
+				return nil, fmt.Errorf("ICO с %d битами на пиксель не поддерживается", bpp)
+			}
+			if (bpp != 32 || !hasAlpha) && mask != nil {
+				m := mask[(height-1-y)*maskStride+x/8]
+				if m&(0x80>>(x%8)) != 0 {
+					c.A = 0
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,170 @@
+package icons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	xdraw "golang.org/x/image/draw"
+)
+
+// Меняется вместе со способом нормализации, чтобы не брать из кэша старые результаты
+const normalizeVersion = 1
+
+// Normalize приводит иконку к PNG размером Size x Size: декодирует PNG, JPEG,
+// GIF, ICO или SVG, вписывает с сохранением пропорций и прозрачными полями,
+// отбрасывает метаданные и сжимает. Результат кладётся в cacheDir под хэшем
+// исходного файла, повторный вызов для того же содержимого ничего не делает.
+func Normalize(path, cacheDir string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d/%d\n", normalizeVersion, Size)
+	hash.Write(data)
+	result := filepath.Join(cacheDir, hex.EncodeToString(hash.Sum(nil))[:32]+".png")
+	if _, err := os.Stat(result); err == nil {
+		return result, nil
+	}
+
+	img, err := Decode(data, path)
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать иконку %s: %w", path, err)
+	}
+	if err := writePNG(result, img); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// writePNG сжимает картинку и атомарно записывает её в path
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, compact(img)); err != nil {
+		return fmt.Errorf("png encode error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write temp file error: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file error: %w", err)
+	}
+	return nil
+}
+
+// Decode декодирует картинку и вписывает её в Size x Size.
+// SVG рисуется сразу в нужном размере, остальные форматы масштабируются.
+func Decode(data []byte, name string) (image.Image, error) {
+	if isSVG(data, name) {
+		return renderSVG(data)
+	}
+	var img image.Image
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".ico") {
+		img, err = decodeICO(data)
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Fit(img), nil
+}
+
+// Fit вписывает картинку в Size x Size по центру, поля остаются прозрачными
+func Fit(src image.Image) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	bounds := src.Bounds()
+	rect := fitRect(bounds.Dx(), bounds.Dy())
+	if rect.Size() == bounds.Size() {
+		draw.Draw(dst, rect, src, bounds.Min, draw.Src)
+	} else {
+		xdraw.CatmullRom.Scale(dst, rect, src, bounds, draw.Src, nil)
+	}
+	return dst
+}
+
+// compact переводит картинку в палитру, если в ней не больше 256 цветов:
+// такой PNG заметно меньше
+func compact(img image.Image) image.Image {
+	bounds := img.Bounds()
+	index := make(map[color.NRGBA]uint8)
+	var palette color.Palette
+	paletted := image.NewPaletted(bounds, nil)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i, ok := index[c]
+			if !ok {
+				if len(palette) == 256 {
+					return img
+				}
+				i = uint8(len(palette))
+				index[c] = i
+				palette = append(palette, c)
+			}
+			paletted.SetColorIndex(x, y, i)
+		}
+	}
+	paletted.Palette = palette
+	return paletted
+}
+
+func fitRect(w, h int) image.Rectangle {
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}
+	}
+	fw, fh := Size, Size
+	if w > h {
+		fh = Size * h / w
+	} else if h > w {
+		fw = Size * w / h
+	}
+	x, y := (Size-fw)/2, (Size-fh)/2
+	return image.Rect(x, y, x+fw, y+fh)
+}
+
+func isSVG(data []byte, name string) bool {
+	if strings.EqualFold(filepath.Ext(name), ".svg") {
+		return true
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.Contains(head, []byte("<svg"))
+}
+
+func renderSVG(data []byte) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	w, h := int(icon.ViewBox.W), int(icon.ViewBox.H)
+	if w <= 0 || h <= 0 {
+		w, h = Size, Size
+	}
+	rect := fitRect(w, h)
+	icon.SetTarget(float64(rect.Min.X), float64(rect.Min.Y), float64(rect.Dx()), float64(rect.Dy()))
+	img := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	scanner := rasterx.NewScannerGV(Size, Size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(Size, Size, scanner), 1)
+	return img, nil
+}
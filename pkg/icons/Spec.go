@@ -0,0 +1,101 @@
+package icons
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Размер иконки кнопки пульта
+const Size = 196
+
+// Spec описывает иконку, которую рисует генератор. Слои рисуются по порядку:
+// фон, картинка, текст, значки.
+type Spec struct {
+	// Цвет фона "#rrggbb" или "#rrggbbaa"
+	Background string `json:"background,omitempty"`
+	// Градиент вместо сплошного фона: два и больше цветов
+	Gradient []string `json:"gradient,omitempty"`
+	// Направление градиента: vertical (по умолчанию), horizontal, diagonal
+	GradientDirection string `json:"gradient_direction,omitempty"`
+	// Картинка поверх фона: путь относительно папки иконок или абсолютный
+	Image string `json:"image,omitempty"`
+	// Доля иконки, которую занимает картинка, по умолчанию 1
+	ImageScale float64 `json:"image_scale,omitempty"`
+	// Текст или символ; строки разделяются \n
+	Text string `json:"text,omitempty"`
+	// Шрифт: regular, bold, mono или путь к TTF/OTF (например, для эмодзи)
+	Font string `json:"font,omitempty"`
+	// Размер шрифта в пикселях; длинный текст уменьшается, чтобы поместиться
+	FontSize float64 `json:"font_size,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	// Положение текста: top, middle (по умолчанию), bottom
+	Align  string  `json:"align,omitempty"`
+	Badges []Badge `json:"badges,omitempty"`
+}
+
+// Badge — кружок с коротким текстом в углу иконки
+type Badge struct {
+	Text       string `json:"text,omitempty"`
+	Color      string `json:"color,omitempty"`
+	Background string `json:"background,omitempty"`
+	// Угол: top-right (по умолчанию), top-left, bottom-right, bottom-left
+	Position string `json:"position,omitempty"`
+}
+
+const (
+	DefaultFontSize   = 64
+	DefaultColor      = "#ffffff"
+	DefaultBackground = "#000000"
+	DefaultBadgeColor = "#e53935"
+)
+
+// ParseColor разбирает цвет вида #rgb, #rrggbb или #rrggbbaa
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("неверный цвет %q", value)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("неверный цвет %q", value)
+	}
+	return color.RGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// Validate проверяет цвета и параметры спецификации
+func (s *Spec) Validate() error {
+	colors := append([]string{s.Background, s.Color}, s.Gradient...)
+	for _, badge := range s.Badges {
+		colors = append(colors, badge.Color, badge.Background)
+	}
+	for _, c := range colors {
+		if c == "" {
+			continue
+		}
+		if _, err := ParseColor(c); err != nil {
+			return err
+		}
+	}
+	if len(s.Gradient) == 1 {
+		return fmt.Errorf("в градиенте должно быть не меньше двух цветов")
+	}
+	switch s.GradientDirection {
+	case "", "vertical", "horizontal", "diagonal":
+	default:
+		return fmt.Errorf("неизвестное направление градиента %q", s.GradientDirection)
+	}
+	switch s.Align {
+	case "", "top", "middle", "center", "bottom":
+	default:
+		return fmt.Errorf("неизвестное положение текста %q", s.Align)
+	}
+	return nil
+}
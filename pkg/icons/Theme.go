@@ -0,0 +1,301 @@
+package icons
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bjaka-max/dispeys/pkg/xdg"
+)
+
+// Префиксы иконок, которые ищутся в системе, а не в папке иконок
+const (
+	// theme:utilities-terminal — иконка из текущей темы по спецификации XDG
+	ThemePrefix = "theme:"
+	// app:org.gnome.Nautilus — иконка из .desktop-файла приложения
+	AppPrefix = "app:"
+)
+
+// IsSystemIcon — иконку нужно искать через ResolveIcon
+func IsSystemIcon(icon string) bool {
+	return strings.HasPrefix(icon, ThemePrefix) || strings.HasPrefix(icon, AppPrefix)
+}
+
+// Расширения в порядке предпочтения из спецификации (xpm не поддерживается)
+var themeExtensions = []string{".png", ".svg"}
+
+type themeDir struct {
+	path      string
+	kind      string
+	size      int
+	minSize   int
+	maxSize   int
+	threshold int
+	scale     int
+}
+
+type iconTheme struct {
+	name     string
+	inherits []string
+	dirs     []themeDir
+}
+
+var (
+	themesMu     sync.Mutex
+	themes       = map[string]*iconTheme{}
+	resolved     = map[string]string{}
+	currentTheme string
+)
+
+// ResolveIcon находит файл для theme:имя или app:приложение,
+// выбирая вариант, ближайший к размеру кнопки
+func ResolveIcon(icon string) (string, error) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	if path, ok := resolved[icon]; ok {
+		return path, nil
+	}
+
+	var path string
+	switch {
+	case strings.HasPrefix(icon, ThemePrefix):
+		name := strings.TrimPrefix(icon, ThemePrefix)
+		path = lookupIcon(name)
+		if path == "" {
+			return "", fmt.Errorf("иконка %q не найдена в теме %s", name, themeName())
+		}
+	case strings.HasPrefix(icon, AppPrefix):
+		id := strings.TrimPrefix(icon, AppPrefix)
+		desktopFile := xdg.FindDesktopFile(id)
+		if desktopFile == "" {
+			return "", fmt.Errorf("приложение %q не найдено", id)
+		}
+		entry, err := xdg.ReadDesktopEntry(desktopFile)
+		if err != nil {
+			return "", err
+		}
+		name := entry["Icon"]
+		if name == "" {
+			return "", fmt.Errorf("у приложения %q нет иконки", id)
+		}
+		if filepath.IsAbs(name) {
+			path = name
+		} else {
+			path = lookupIcon(name)
+		}
+		if path == "" {
+			return "", fmt.Errorf("иконка %q приложения %q не найдена в теме %s", name, id, themeName())
+		}
+	default:
+		return "", fmt.Errorf("неизвестная системная иконка %q", icon)
+	}
+	resolved[icon] = path
+	return path, nil
+}
+
+// themeName возвращает тему иконок из настроек GTK, по умолчанию hicolor
+func themeName() string {
+	if currentTheme != "" {
+		return currentTheme
+	}
+	currentTheme = "hicolor"
+	for _, version := range []string{"gtk-4.0", "gtk-3.0"} {
+		settings, err := xdg.ReadIniGroup(filepath.Join(xdg.ConfigHome(), version, "settings.ini"), "Settings")
+		if err == nil && settings["gtk-icon-theme-name"] != "" {
+			currentTheme = settings["gtk-icon-theme-name"]
+			return currentTheme
+		}
+	}
+	out, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "icon-theme").Output()
+	if err == nil {
+		if name := strings.Trim(strings.TrimSpace(string(out)), "'"); name != "" {
+			currentTheme = name
+		}
+	}
+	return currentTheme
+}
+
+// iconBaseDirs — каталоги, в которых лежат темы иконок
+func iconBaseDirs() []string {
+	home, _ := os.UserHomeDir()
+	dirs := []string{filepath.Join(home, ".icons")}
+	for _, dir := range xdg.DataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+	return dirs
+}
+
+func lookupIcon(name string) string {
+	visited := map[string]bool{}
+	if path := lookupInTheme(themeName(), name, visited); path != "" {
+		return path
+	}
+	if path := lookupInTheme("hicolor", name, visited); path != "" {
+		return path
+	}
+	for _, dir := range append(iconBaseDirs(), "/usr/share/pixmaps") {
+		for _, ext := range themeExtensions {
+			path := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func lookupInTheme(name, icon string, visited map[string]bool) string {
+	if visited[name] {
+		return ""
+	}
+	visited[name] = true
+	theme := loadTheme(name)
+	if theme == nil {
+		return ""
+	}
+	if path := theme.lookup(icon); path != "" {
+		return path
+	}
+	for _, parent := range theme.inherits {
+		if path := lookupInTheme(parent, icon, visited); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// lookup выбирает вариант иконки по алгоритму спецификации: сначала каталог,
+// подходящий под Size, иначе ближайший по размеру
+func (t *iconTheme) lookup(icon string) string {
+	for _, dir := range t.dirs {
+		if !dir.matches(Size) {
+			continue
+		}
+		for _, ext := range themeExtensions {
+			path := filepath.Join(dir.path, icon+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	best, bestDistance := "", -1
+	for _, dir := range t.dirs {
+		for _, ext := range themeExtensions {
+			path := filepath.Join(dir.path, icon+ext)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if distance := dir.distance(Size); bestDistance < 0 || distance < bestDistance {
+				best, bestDistance = path, distance
+			}
+		}
+	}
+	return best
+}
+
+func (d themeDir) matches(size int) bool {
+	switch d.kind {
+	case "Fixed":
+		return size == d.size
+	case "Scalable":
+		return d.minSize <= size && size <= d.maxSize
+	default:
+		return d.size-d.threshold <= size && size <= d.size+d.threshold
+	}
+}
+
+func (d themeDir) distance(size int) int {
+	switch d.kind {
+	case "Fixed":
+		if size > d.size {
+			return size - d.size
+		}
+		return d.size - size
+	case "Scalable":
+		if size < d.minSize {
+			return d.minSize - size
+		}
+		if size > d.maxSize {
+			return size - d.maxSize
+		}
+		return 0
+	default:
+		if size < d.size-d.threshold {
+			return d.minSize - size
+		}
+		if size > d.size+d.threshold {
+			return size - d.maxSize
+		}
+		return 0
+	}
+}
+
+// loadTheme читает index.theme темы и раскладывает её каталоги по всем базовым папкам
+func loadTheme(name string) *iconTheme {
+	if theme, ok := themes[name]; ok {
+		return theme
+	}
+	themes[name] = nil
+
+	var groups map[string]map[string]string
+	for _, base := range iconBaseDirs() {
+		var err error
+		groups, err = xdg.ReadIni(filepath.Join(base, name, "index.theme"))
+		if err == nil {
+			break
+		}
+	}
+	if groups == nil {
+		return nil
+	}
+	info := groups["Icon Theme"]
+	theme := &iconTheme{name: name}
+	for _, parent := range strings.Split(info["Inherits"], ",") {
+		if parent = strings.TrimSpace(parent); parent != "" {
+			theme.inherits = append(theme.inherits, parent)
+		}
+	}
+	subdirs := info["Directories"]
+	if scaled := info["ScaledDirectories"]; scaled != "" {
+		subdirs += "," + scaled
+	}
+	for _, subdir := range strings.Split(subdirs, ",") {
+		subdir = strings.TrimSpace(subdir)
+		group, ok := groups[subdir]
+		if subdir == "" || !ok {
+			continue
+		}
+		dir := themeDir{
+			kind:      group["Type"],
+			size:      atoiOr(group["Size"], 0),
+			scale:     atoiOr(group["Scale"], 1),
+			threshold: atoiOr(group["Threshold"], 2),
+		}
+		// Варианты для HiDPI-экранов не нужны: у кнопки свой размер
+		if dir.size == 0 || dir.scale != 1 {
+			continue
+		}
+		dir.minSize = atoiOr(group["MinSize"], dir.size)
+		dir.maxSize = atoiOr(group["MaxSize"], dir.size)
+		for _, base := range iconBaseDirs() {
+			path := filepath.Join(base, name, subdir)
+			if _, err := os.Stat(path); err == nil {
+				dir.path = path
+				theme.dirs = append(theme.dirs, dir)
+			}
+		}
+	}
+	themes[name] = theme
+	return theme
+}
+
+func atoiOr(value string, fallback int) int {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return fallback
+}
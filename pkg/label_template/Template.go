@@ -0,0 +1,143 @@
+package labeltemplate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Сколько может выполняться команда из {{exec}}
+const ExecTimeout = 5 * time.Second
+
+// Template — подпись кнопки в синтаксисе text/template, например
+// `{{.cpu}}%`, `{{time "15:04"}}` или `{{exec "git branch --show-current"}}`
+type Template struct {
+	source string
+	tmpl   *template.Template
+}
+
+var funcs = template.FuncMap{
+	"time":  formatTime,
+	"exec":  execCommand,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("label").Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в шаблоне подписи %q: %w", text, err)
+	}
+	return &Template{source: text, tmpl: tmpl}, nil
+}
+
+// IsStatic — в шаблоне нет подстановок, подпись не меняется
+func (t *Template) IsStatic() bool {
+	for _, node := range t.tmpl.Tree.Root.Nodes {
+		if node.Type() != parse.NodeText {
+			return false
+		}
+	}
+	return true
+}
+
+// Fields возвращает имена данных, на которые ссылается шаблон (.cpu -> "cpu"),
+// чтобы не собирать дорогие данные, которые не нужны
+func (t *Template) Fields() map[string]bool {
+	fields := make(map[string]bool)
+	collectFields(t.tmpl.Tree.Root, fields, true)
+	return fields
+}
+
+func (t *Template) Execute(data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("ошибка подписи %q: %w", t.source, err)
+	}
+	// Отсутствующие данные (например, нет видеокарты) дают пустую строку
+	text := strings.ReplaceAll(buf.String(), "<no value>", "")
+	return strings.TrimSpace(text), nil
+}
+
+func (t *Template) String() string {
+	return t.source
+}
+
+// collectFields собирает поля данных шаблона. top — точка в node ещё
+// указывает на сами данные: внутри with и range .x относится к вложенному
+// значению, и к данным обращаются только через $.x.
+func collectFields(node parse.Node, fields map[string]bool, top bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, fields, top)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, fields, top)
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, fields, top, top)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, fields, top, false)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, fields, top, false)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, fields, top)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, fields, top)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, fields, top)
+		}
+	case *parse.FieldNode:
+		if top {
+			fields[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			fields[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, fields, top)
+	}
+}
+
+// collectBranch обходит if, with или range: условие и else выполняются
+// с внешней точкой, тело — с точкой, которая указывает на данные, только если inner
+func collectBranch(n *parse.BranchNode, fields map[string]bool, top, inner bool) {
+	collectFields(n.Pipe, fields, top)
+	collectFields(n.List, fields, inner)
+	collectFields(n.ElseList, fields, top)
+}
+
+// formatTime форматирует текущее время в раскладке Go, по умолчанию 15:04
+func formatTime(layout ...string) string {
+	if len(layout) == 0 {
+		return time.Now().Format("15:04")
+	}
+	return time.Now().Format(layout[0])
+}
+
+// execCommand выполняет команду и возвращает её вывод без пробелов по краям
+func execCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ExecTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		return "", fmt.Errorf("%q: %w", command, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
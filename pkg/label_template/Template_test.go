@@ -0,0 +1,129 @@
+package labeltemplate
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"Build", ""},
+		{"{{.cpu}}%", ""},
+		{`{{time "15:04"}} {{upper .process}}`, ""},
+		{"{{.cpu", "ошибка в шаблоне подписи"},
+		{"{{unknown .cpu}}", `function "unknown" not defined`},
+		{"{{if .cpu}}", "ошибка в шаблоне подписи"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.text)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("Parse(%q): %v", tt.text, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q): ошибка %v, ожидалось %q", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestIsStatic(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"", true},
+		{"Build", true},
+		{"{{/* комментарий */}}Build", true},
+		{"{{.cpu}}%", false},
+		{`{{time}}`, false},
+		{`{{if true}}a{{end}}`, false},
+	}
+	for _, tt := range tests {
+		tmpl, err := Parse(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tmpl.IsStatic(); got != tt.want {
+			t.Errorf("IsStatic(%q) = %v", tt.text, got)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Build", nil},
+		{"{{.cpu}}%", []string{"cpu"}},
+		{"{{.cpu}}/{{.mem}}", []string{"cpu", "mem"}},
+		{"{{printf \"%d\" .gpu | print}}", []string{"gpu"}},
+		{"{{if gt .cpu 50}}{{.mem}}{{else}}{{.gpu}}{{end}}", []string{"cpu", "gpu", "mem"}},
+		// Внутри with и range точка — вложенное значение
+		{"{{with .process}}{{.cpu}}{{end}}", []string{"process"}},
+		{"{{range .items}}{{.name}}{{else}}{{.state}}{{end}}", []string{"items", "state"}},
+		{"{{with .process}}{{$.cpu}}{{end}}", []string{"cpu", "process"}},
+		{"{{$.mem}}", []string{"mem"}},
+		{"{{$x := .cpu}}{{$x}}", []string{"cpu"}},
+	}
+	for _, tt := range tests {
+		tmpl, err := Parse(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for name := range tmpl.Fields() {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fields(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	data := map[string]interface{}{"cpu": 42, "process": "code"}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{.cpu}}%", "42%"},
+		// Данных нет: пустая строка вместо <no value>
+		{"GPU {{.gpu}}%", "GPU %"},
+		{"{{.gpu}}", ""},
+		{"{{with .process}}{{upper .}} {{$.cpu}}{{end}}", "CODE 42"},
+		{"{{if .gpu}}{{.gpu}}{{else}}нет{{end}}", "нет"},
+		{"  {{.process}}\n", "code"},
+		{`{{exec "echo branch"}}`, "branch"},
+	}
+	for _, tt := range tests {
+		tmpl, err := Parse(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tmpl.Execute(data)
+		if err != nil {
+			t.Errorf("Execute(%q): %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Execute(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+		}
+	}
+
+	tmpl, err := Parse(`{{exec "exit 3"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Execute(data); err == nil {
+		t.Error("ошибка команды exec не возвращена")
+	}
+}
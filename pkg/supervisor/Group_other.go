@@ -0,0 +1,18 @@
+//go:build !unix
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killGroup без групп процессов останавливает только сам процесс
+func killGroup(process *os.Process, force bool) error {
+	if process == nil {
+		return nil
+	}
+	return process.Kill()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup запускает процесс в своей группе, чтобы по таймауту
+// можно было остановить и его потомков
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killGroup посылает группе процесса SIGTERM или, если force, SIGKILL
+func killGroup(process *os.Process, force bool) error {
+	if process == nil {
+		return nil
+	}
+	signal := syscall.SIGTERM
+	if force {
+		signal = syscall.SIGKILL
+	}
+	return syscall.Kill(-process.Pid, signal)
+}
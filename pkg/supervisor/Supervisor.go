@@ -0,0 +1,271 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRunning — процесс с тем же ключом ещё не завершился
+var ErrRunning = errors.New("уже выполняется")
+
+// Сколько ждать завершения группы процессов после SIGTERM, прежде чем убить её
+const KillDelay = 2 * time.Second
+
+// Сколько последних байт вывода попадает в текст ошибки
+const outputTail = 512
+
+// Сколько после завершения процесса ждать конца его вывода. Дольше вывод
+// держат только потомки, оставшиеся в фоне: их вывод продолжает попадать
+// в лог, но результат процесса их не ждёт.
+const outputGrace = 100 * time.Millisecond
+
+// Spec — процесс, который нужно запустить
+type Spec struct {
+	// Подпись строк вывода в логе
+	Name string
+	Path string
+	Args []string
+	// Переменные "KEY=value", которые добавляются к окружению приложения
+	// и заменяют одноимённые
+	Env []string
+	// Рабочий каталог; пустой — текущий каталог приложения
+	Dir string
+	// Пока работает процесс с непустым ключом, второй с тем же ключом
+	// не запускается
+	Key string
+}
+
+// Supervisor запускает процессы в отдельных группах, пишет их вывод в лог
+// построчно и дожидается их завершения, чтобы не оставалось зомби. При
+// отмене контекста останавливается вся группа процессов, а не только sh.
+type Supervisor struct {
+	// Куда пишется вывод процессов
+	Log io.Writer
+
+	mu      sync.Mutex
+	running map[*Process]struct{}
+	keys    map[string]*Process
+}
+
+// Default — общий супервизор для всех запусков приложения
+var Default = New()
+
+func New() *Supervisor {
+	return &Supervisor{
+		Log:     os.Stdout,
+		running: make(map[*Process]struct{}),
+		keys:    make(map[string]*Process),
+	}
+}
+
+// Process — запущенный процесс
+type Process struct {
+	Spec Spec
+	Pid  int
+
+	ctx  context.Context
+	cmd  *exec.Cmd
+	log  *lineWriter
+	tail *tailBuffer
+	// Закрывается, когда вывод прочитан до конца
+	outputDone chan struct{}
+	done       chan struct{}
+	err        error
+}
+
+// Start запускает процесс и сразу возвращается; завершения ждёт горутина
+// супервизора. Отмена ctx останавливает группу процесса.
+func (s *Supervisor) Start(ctx context.Context, spec Spec) (*Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if spec.Key != "" {
+		if _, ok := s.keys[spec.Key]; ok {
+			return nil, fmt.Errorf("%s: %w", spec.Name, ErrRunning)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, spec.Path, spec.Args...)
+	if len(spec.Env) > 0 {
+		cmd.Env = append(os.Environ(), spec.Env...)
+	}
+	cmd.Dir = spec.Dir
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killGroup(cmd.Process, false)
+	}
+	// Если процесс не завершился по SIGTERM, через KillDelay его убьют.
+	// Процессам, которые нельзя отменить (запущенные программы), это не нужно.
+	if ctx.Done() != nil {
+		cmd.WaitDelay = KillDelay
+	}
+	p := &Process{
+		Spec:       spec,
+		ctx:        ctx,
+		cmd:        cmd,
+		log:        &lineWriter{prefix: "[" + spec.Name + "] ", w: s.Log},
+		tail:       &tailBuffer{},
+		outputDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	// Свой канал вместо каналов exec: exec закрывает их после завершения
+	// процесса, и потомки, оставшиеся в фоне ("sh -c 'app &'"), погибали
+	// от SIGPIPE на первой же записи. Этот канал читается, пока его не
+	// закроет последний из них.
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec.Name, err)
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	err = cmd.Start()
+	writer.Close()
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s: %w", spec.Name, err)
+	}
+	go func() {
+		io.Copy(io.MultiWriter(p.log, p.tail), reader)
+		reader.Close()
+		p.log.flush()
+		close(p.outputDone)
+	}()
+	p.Pid = cmd.Process.Pid
+	s.running[p] = struct{}{}
+	if spec.Key != "" {
+		s.keys[spec.Key] = p
+	}
+	go s.wait(p)
+	return p, nil
+}
+
+// Run запускает процесс и ждёт его завершения
+func (s *Supervisor) Run(ctx context.Context, spec Spec) error {
+	p, err := s.Start(ctx, spec)
+	if err != nil {
+		return err
+	}
+	return p.Wait()
+}
+
+// Running возвращает процессы, которые ещё не завершились
+func (s *Supervisor) Running() []*Process {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]*Process, 0, len(s.running))
+	for p := range s.running {
+		result = append(result, p)
+	}
+	return result
+}
+
+func (s *Supervisor) wait(p *Process) {
+	err := p.cmd.Wait()
+	// После остановки по таймауту добиваем всех, кто не завершился по SIGTERM
+	if p.ctx.Err() != nil {
+		_ = killGroup(p.cmd.Process, true)
+	}
+	timer := time.NewTimer(outputGrace)
+	select {
+	case <-p.outputDone:
+	case <-timer.C:
+	}
+	timer.Stop()
+	if err != nil {
+		if tail := p.tail.lastLine(); tail != "" {
+			err = fmt.Errorf("%s: %w: %s", p.Spec.Name, err, tail)
+		} else {
+			err = fmt.Errorf("%s: %w", p.Spec.Name, err)
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.running, p)
+	if p.Spec.Key != "" && s.keys[p.Spec.Key] == p {
+		delete(s.keys, p.Spec.Key)
+	}
+	s.mu.Unlock()
+
+	p.err = err
+	close(p.done)
+}
+
+// Wait ждёт завершения процесса и возвращает ошибку с кодом выхода
+// и последней строкой вывода
+func (p *Process) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// Done закрывается после завершения процесса
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// lineWriter пишет вывод процесса в лог целыми строками с подписью
+type lineWriter struct {
+	prefix string
+	w      io.Writer
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (l *lineWriter) Write(data []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, data...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprintf(l.w, "%s%s\n", l.prefix, l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	// Строка без перевода строки не должна копиться бесконечно
+	if len(l.buf) > 4096 {
+		fmt.Fprintf(l.w, "%s%s\n", l.prefix, l.buf)
+		l.buf = nil
+	}
+	return len(data), nil
+}
+
+// flush выводит последнюю строку без перевода строки
+func (l *lineWriter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) > 0 {
+		fmt.Fprintf(l.w, "%s%s\n", l.prefix, l.buf)
+		l.buf = nil
+	}
+}
+
+// tailBuffer хранит последние outputTail байт вывода
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (t *tailBuffer) Write(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, data...)
+	if len(t.buf) > outputTail {
+		t.buf = t.buf[len(t.buf)-outputTail:]
+	}
+	return len(data), nil
+}
+
+func (t *tailBuffer) lastLine() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := strings.Split(strings.TrimSpace(string(t.buf)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
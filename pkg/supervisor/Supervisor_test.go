@@ -0,0 +1,160 @@
+//go:build unix
+
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer — лог, в который пишут горутины супервизора
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestSupervisor() (*Supervisor, *syncBuffer) {
+	log := &syncBuffer{}
+	s := New()
+	s.Log = log
+	return s, log
+}
+
+func waitFile(path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRunOutputAndErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		log     string
+		err     string
+	}{
+		{"успешная команда", "echo hello; echo world >&2", "[test] hello\n[test] world\n", ""},
+		{"строка без перевода строки", "printf tail", "[test] tail\n", ""},
+		{"код выхода и последняя строка", "echo first; echo boom; exit 3", "[test] first\n[test] boom\n", "test: exit status 3: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, log := newTestSupervisor()
+			err := s.Run(context.Background(), Spec{Name: "test", Path: "sh", Args: []string{"-c", tt.command}})
+			if tt.err == "" && err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+			if got := log.String(); got != tt.log {
+				t.Errorf("лог %q, ожидался %q", got, tt.log)
+			}
+			if n := len(s.Running()); n != 0 {
+				t.Errorf("не завершено процессов: %d", n)
+			}
+		})
+	}
+}
+
+func TestBackgroundChildOutlivesCommand(t *testing.T) {
+	// Потомок пишет в вывод уже после KillDelay от завершения sh
+	marker := filepath.Join(t.TempDir(), "marker")
+	command := fmt.Sprintf("(sleep %.1f; echo hello; touch %s) &", (KillDelay + 500*time.Millisecond).Seconds(), marker)
+	s, log := newTestSupervisor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	if err := s.Run(ctx, Spec{Name: "bg", Path: "sh", Args: []string{"-c", command}}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run ждал фонового потомка %v", elapsed)
+	}
+	if !waitFile(marker, KillDelay+3*time.Second) {
+		t.Fatal("фоновый потомок не дожил до конца")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(log.String(), "[bg] hello") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(log.String(), "[bg] hello") {
+		t.Errorf("вывод потомка не попал в лог: %q", log.String())
+	}
+}
+
+func TestStartedProgramKeepsRunning(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	s, _ := newTestSupervisor()
+	p, err := s.Start(context.Background(), Spec{Name: "app", Path: "sh", Args: []string{"-c", "sleep 0.2; echo ready; touch " + marker}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunTimeoutKillsGroup(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	s, _ := newTestSupervisor()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.Run(ctx, Spec{Name: "slow", Path: "sh", Args: []string{"-c", "(sleep 1; touch " + marker + ") & sleep 10"}})
+	if err == nil {
+		t.Fatal("команда не остановлена")
+	}
+	if elapsed := time.Since(start); elapsed > KillDelay+time.Second {
+		t.Errorf("остановка заняла %v", elapsed)
+	}
+	if waitFile(marker, 1500*time.Millisecond) {
+		t.Error("потомок пережил остановку группы")
+	}
+}
+
+func TestSingleInstance(t *testing.T) {
+	s, _ := newTestSupervisor()
+	spec := Spec{Name: "once", Path: "sleep", Args: []string{"0.3"}, Key: "once"}
+	p, err := s.Start(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Start(context.Background(), spec); !errors.Is(err, ErrRunning) {
+		t.Errorf("второй запуск: %v", err)
+	}
+	p.Wait()
+	if err := s.Run(context.Background(), spec); err != nil {
+		t.Errorf("запуск после завершения: %v", err)
+	}
+}
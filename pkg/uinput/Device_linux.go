@@ -0,0 +1,95 @@
+package uinput
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// DevicePath — устройство ядра для создания виртуальных устройств ввода
+const DevicePath = "/dev/uinput"
+
+// ioctl из linux/uinput.h
+const (
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+)
+
+// Длина имени в struct uinput_user_dev
+const maxNameSize = 80
+
+// Device — виртуальная клавиатура в /dev/uinput
+type Device struct {
+	file *os.File
+}
+
+// Open создаёт виртуальную клавиатуру с именем name. Нужен доступ на запись
+// к /dev/uinput, обычно через группу input или правило udev.
+func Open(name string) (*Device, error) {
+	file, err := os.OpenFile(DevicePath, os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %w", DevicePath, err)
+	}
+	d := &Device{file: file}
+	if err := d.setup(name); err != nil {
+		file.Close()
+		return nil, err
+	}
+	// Пока окружение не увидело новое устройство, нажатия теряются
+	time.Sleep(200 * time.Millisecond)
+	return d, nil
+}
+
+func (d *Device) setup(name string) error {
+	fd := int(d.file.Fd())
+	if err := unix.IoctlSetInt(fd, uiSetEvBit, EV_KEY); err != nil {
+		return fmt.Errorf("UI_SET_EVBIT: %w", err)
+	}
+	for code := 1; code <= keyMax; code++ {
+		if err := unix.IoctlSetInt(fd, uiSetKeyBit, code); err != nil {
+			return fmt.Errorf("UI_SET_KEYBIT: %w", err)
+		}
+	}
+
+	// struct uinput_user_dev: имя, input_id, ff_effects_max и четыре
+	// массива осей, которые клавиатуре не нужны
+	dev := make([]byte, maxNameSize+8+4+4*64*4)
+	copy(dev[:maxNameSize-1], name)
+	binary.NativeEndian.PutUint16(dev[maxNameSize:], unix.BUS_VIRTUAL)
+	binary.NativeEndian.PutUint16(dev[maxNameSize+2:], 1)
+	binary.NativeEndian.PutUint16(dev[maxNameSize+4:], 1)
+	binary.NativeEndian.PutUint16(dev[maxNameSize+6:], 1)
+	if _, err := d.file.Write(dev); err != nil {
+		return fmt.Errorf("не удалось описать устройство uinput: %w", err)
+	}
+	if err := unix.IoctlSetInt(fd, uiDevCreate, 0); err != nil {
+		return fmt.Errorf("UI_DEV_CREATE: %w", err)
+	}
+	return nil
+}
+
+// Размер struct input_event: timeval, type, code, value
+var eventSize = int(unsafe.Sizeof(unix.Timeval{})) + 8
+
+// WriteEvent отправляет событие ядру. Время события ядро ставит само.
+func (d *Device) WriteEvent(typ, code uint16, value int32) error {
+	event := make([]byte, eventSize)
+	offset := eventSize - 8
+	binary.NativeEndian.PutUint16(event[offset:], typ)
+	binary.NativeEndian.PutUint16(event[offset+2:], code)
+	binary.NativeEndian.PutUint32(event[offset+4:], uint32(value))
+	_, err := d.file.Write(event)
+	return err
+}
+
+// Close удаляет виртуальное устройство
+func (d *Device) Close() error {
+	_ = unix.IoctlSetInt(int(d.file.Fd()), uiDevDestroy, 0)
+	return d.file.Close()
+}
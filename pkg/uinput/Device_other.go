@@ -0,0 +1,22 @@
+//go:build !linux
+
+package uinput
+
+import "errors"
+
+var errUnsupported = errors.New("uinput поддерживается только в Linux")
+
+// Device доступен только в Linux
+type Device struct{}
+
+func Open(name string) (*Device, error) {
+	return nil, errUnsupported
+}
+
+func (d *Device) WriteEvent(typ, code uint16, value int32) error {
+	return errUnsupported
+}
+
+func (d *Device) Close() error {
+	return nil
+}
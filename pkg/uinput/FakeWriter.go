@@ -0,0 +1,56 @@
+package uinput
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Event — событие ввода, записанное FakeWriter
+type Event struct {
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+func (e Event) String() string {
+	if e.Type == EV_SYN {
+		return "sync"
+	}
+	if e.Value == 0 {
+		return fmt.Sprintf("up %d", e.Code)
+	}
+	return fmt.Sprintf("down %d", e.Code)
+}
+
+// FakeWriter запоминает события вместо отправки в ядро, чтобы нажатия
+// можно было проверить без /dev/uinput
+type FakeWriter struct {
+	mu     sync.Mutex
+	events []Event
+	// Если задана, WriteEvent возвращает эту ошибку
+	Err error
+}
+
+func (w *FakeWriter) WriteEvent(typ, code uint16, value int32) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.Err != nil {
+		return w.Err
+	}
+	w.events = append(w.events, Event{Type: typ, Code: code, Value: value})
+	return nil
+}
+
+// Events возвращает записанные события
+func (w *FakeWriter) Events() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Event(nil), w.events...)
+}
+
+// Reset забывает записанные события
+func (w *FakeWriter) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = nil
+}
@@ -0,0 +1,93 @@
+package uinput
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Типы событий и синхронизация из linux/input-event-codes.h
+const (
+	EV_SYN     = 0x00
+	EV_KEY     = 0x01
+	SYN_REPORT = 0
+)
+
+// EventWriter принимает события ввода: устройство uinput или FakeWriter
+type EventWriter interface {
+	WriteEvent(typ, code uint16, value int32) error
+}
+
+// Пауза между нажатиями, как у xdotool по умолчанию
+const DefaultKeyDelay = 12 * time.Millisecond
+
+// Keyboard нажимает комбинации клавиш и печатает текст
+type Keyboard struct {
+	w     EventWriter
+	Delay time.Duration
+
+	// Нажатия из разных горутин не должны перемешиваться
+	mu sync.Mutex
+}
+
+func NewKeyboard(w EventWriter) *Keyboard {
+	return &Keyboard{w: w, Delay: DefaultKeyDelay}
+}
+
+// Keys нажимает клавиши, записанные в синтаксисе xdotool key
+func (k *Keyboard) Keys(ctx context.Context, s string) error {
+	combos, err := ParseKeys(s)
+	if err != nil {
+		return err
+	}
+	return k.Press(ctx, combos...)
+}
+
+// Type печатает текст
+func (k *Keyboard) Type(ctx context.Context, text string) error {
+	combos, err := TextCombos(text)
+	if err != nil {
+		return err
+	}
+	return k.Press(ctx, combos...)
+}
+
+// Press нажимает и отпускает комбинации по очереди. Отмена ctx
+// останавливает нажатия между комбинациями, поэтому ни одна клавиша
+// не остаётся зажатой.
+func (k *Keyboard) Press(ctx context.Context, combos ...Combo) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i, combo := range combos {
+		if i > 0 {
+			timer := time.NewTimer(k.Delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, code := range combo {
+			if err := k.key(code, 1); err != nil {
+				return err
+			}
+		}
+		for j := len(combo) - 1; j >= 0; j-- {
+			if err := k.key(combo[j], 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// key отправляет нажатие (1) или отпускание (0) клавиши
+func (k *Keyboard) key(code uint16, value int32) error {
+	if err := k.w.WriteEvent(EV_KEY, code, value); err != nil {
+		return err
+	}
+	return k.w.WriteEvent(EV_SYN, SYN_REPORT, 0)
+}
@@ -0,0 +1,103 @@
+package uinput
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func down(code uint16) Event { return Event{Type: EV_KEY, Code: code, Value: 1} }
+func up(code uint16) Event   { return Event{Type: EV_KEY, Code: code, Value: 0} }
+
+var syn = Event{Type: EV_SYN, Code: SYN_REPORT}
+
+// withSync добавляет после каждого события синхронизацию, как Keyboard
+func withSync(events ...Event) []Event {
+	var result []Event
+	for _, event := range events {
+		result = append(result, event, syn)
+	}
+	return result
+}
+
+func TestKeyboardPressRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(k *Keyboard) error
+		want []Event
+	}{
+		{"комбинация отпускается в обратном порядке", func(k *Keyboard) error {
+			return k.Keys(context.Background(), "ctrl+shift+t")
+		}, withSync(down(KEY_LEFTCTRL), down(KEY_LEFTSHIFT), down(20), up(20), up(KEY_LEFTSHIFT), up(KEY_LEFTCTRL))},
+		{"комбинации по очереди", func(k *Keyboard) error {
+			return k.Keys(context.Background(), "ctrl+a Delete")
+		}, withSync(down(KEY_LEFTCTRL), down(30), up(30), up(KEY_LEFTCTRL), down(KEY_DELETE), up(KEY_DELETE))},
+		{"текст", func(k *Keyboard) error {
+			return k.Type(context.Background(), "aB")
+		}, withSync(down(30), up(30), down(KEY_LEFTSHIFT), down(48), up(48), up(KEY_LEFTSHIFT))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &FakeWriter{}
+			k := NewKeyboard(w)
+			k.Delay = 0
+			if err := tt.run(k); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Events(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("события %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyboardParseErrorSendsNothing(t *testing.T) {
+	w := &FakeWriter{}
+	k := NewKeyboard(w)
+	if err := k.Keys(context.Background(), "ctrl+c nokey"); err == nil {
+		t.Fatal("неизвестная клавиша принята")
+	}
+	if events := w.Events(); len(events) != 0 {
+		t.Errorf("отправлены события %v", events)
+	}
+}
+
+func TestKeyboardWriteError(t *testing.T) {
+	errWrite := errors.New("write failed")
+	w := &FakeWriter{Err: errWrite}
+	if err := NewKeyboard(w).Keys(context.Background(), "a"); !errors.Is(err, errWrite) {
+		t.Errorf("ошибка %v", err)
+	}
+}
+
+func TestKeyboardCancel(t *testing.T) {
+	w := &FakeWriter{}
+	k := NewKeyboard(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := k.Type(ctx, "abc"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ошибка %v", err)
+	}
+	if events := w.Events(); len(events) != 0 {
+		t.Errorf("после отмены отправлены события %v", events)
+	}
+
+	// Отмена во время паузы: начатая комбинация уже отпущена, следующие не нажимаются
+	k.Delay = time.Hour
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := k.Keys(ctx, "ctrl+a b c"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ошибка %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("отмена заняла %v", elapsed)
+	}
+	want := withSync(down(KEY_LEFTCTRL), down(30), up(30), up(KEY_LEFTCTRL))
+	if got := w.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("события %v, ожидалось %v", got, want)
+	}
+}
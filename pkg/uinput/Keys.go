@@ -0,0 +1,264 @@
+package uinput
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Коды клавиш из linux/input-event-codes.h
+const (
+	KEY_ESC        = 1
+	KEY_1          = 2
+	KEY_0          = 11
+	KEY_MINUS      = 12
+	KEY_EQUAL      = 13
+	KEY_BACKSPACE  = 14
+	KEY_TAB        = 15
+	KEY_LEFTBRACE  = 26
+	KEY_RIGHTBRACE = 27
+	KEY_ENTER      = 28
+	KEY_LEFTCTRL   = 29
+	KEY_SEMICOLON  = 39
+	KEY_APOSTROPHE = 40
+	KEY_GRAVE      = 41
+	KEY_LEFTSHIFT  = 42
+	KEY_BACKSLASH  = 43
+	KEY_COMMA      = 51
+	KEY_DOT        = 52
+	KEY_SLASH      = 53
+	KEY_RIGHTSHIFT = 54
+	KEY_LEFTALT    = 56
+	KEY_SPACE      = 57
+	KEY_CAPSLOCK   = 58
+	KEY_F1         = 59
+	KEY_NUMLOCK    = 69
+	KEY_SCROLLLOCK = 70
+	KEY_F11        = 87
+	KEY_F12        = 88
+	KEY_RIGHTCTRL  = 97
+	KEY_SYSRQ      = 99
+	KEY_RIGHTALT   = 100
+	KEY_HOME       = 102
+	KEY_UP         = 103
+	KEY_PAGEUP     = 104
+	KEY_LEFT       = 105
+	KEY_RIGHT      = 106
+	KEY_END        = 107
+	KEY_DOWN       = 108
+	KEY_PAGEDOWN   = 109
+	KEY_INSERT     = 110
+	KEY_DELETE     = 111
+	KEY_MUTE       = 113
+	KEY_VOLUMEDOWN = 114
+	KEY_VOLUMEUP   = 115
+	KEY_PAUSE      = 119
+	KEY_LEFTMETA   = 125
+	KEY_RIGHTMETA  = 126
+	KEY_COMPOSE    = 127
+	KEY_NEXTSONG   = 163
+	KEY_PLAYPAUSE  = 164
+	KEY_PREVSONG   = 165
+	KEY_STOPCD     = 166
+	KEY_F13        = 183
+	KEY_PAUSECD    = 201
+
+	KEY_BRIGHTNESSDOWN = 224
+	KEY_BRIGHTNESSUP   = 225
+
+	// Старший код, который включается у виртуальной клавиатуры
+	keyMax = 255
+)
+
+// Буквы в порядке кодов клавиш по рядам клавиатуры
+var letterRows = []struct {
+	first   uint16
+	letters string
+}{
+	{16, "qwertyuiop"},
+	{30, "asdfghjkl"},
+	{44, "zxcvbnm"},
+}
+
+// stroke — клавиша и нужен ли для неё shift
+type stroke struct {
+	code  uint16
+	shift bool
+}
+
+// symbol — клавиша с именем keysym из X11 и символом, который она печатает
+type symbol struct {
+	name string
+	char rune
+	stroke
+}
+
+var symbols = []symbol{
+	{"space", ' ', stroke{KEY_SPACE, false}},
+	{"Return", '\n', stroke{KEY_ENTER, false}},
+	{"Tab", '\t', stroke{KEY_TAB, false}},
+	{"minus", '-', stroke{KEY_MINUS, false}},
+	{"underscore", '_', stroke{KEY_MINUS, true}},
+	{"equal", '=', stroke{KEY_EQUAL, false}},
+	{"plus", '+', stroke{KEY_EQUAL, true}},
+	{"bracketleft", '[', stroke{KEY_LEFTBRACE, false}},
+	{"braceleft", '{', stroke{KEY_LEFTBRACE, true}},
+	{"bracketright", ']', stroke{KEY_RIGHTBRACE, false}},
+	{"braceright", '}', stroke{KEY_RIGHTBRACE, true}},
+	{"semicolon", ';', stroke{KEY_SEMICOLON, false}},
+	{"colon", ':', stroke{KEY_SEMICOLON, true}},
+	{"apostrophe", '\'', stroke{KEY_APOSTROPHE, false}},
+	{"quotedbl", '"', stroke{KEY_APOSTROPHE, true}},
+	{"grave", '`', stroke{KEY_GRAVE, false}},
+	{"asciitilde", '~', stroke{KEY_GRAVE, true}},
+	{"backslash", '\\', stroke{KEY_BACKSLASH, false}},
+	{"bar", '|', stroke{KEY_BACKSLASH, true}},
+	{"comma", ',', stroke{KEY_COMMA, false}},
+	{"less", '<', stroke{KEY_COMMA, true}},
+	{"period", '.', stroke{KEY_DOT, false}},
+	{"greater", '>', stroke{KEY_DOT, true}},
+	{"slash", '/', stroke{KEY_SLASH, false}},
+	{"question", '?', stroke{KEY_SLASH, true}},
+	{"exclam", '!', stroke{KEY_1, true}},
+	{"at", '@', stroke{KEY_1 + 1, true}},
+	{"numbersign", '#', stroke{KEY_1 + 2, true}},
+	{"dollar", '$', stroke{KEY_1 + 3, true}},
+	{"percent", '%', stroke{KEY_1 + 4, true}},
+	{"asciicircum", '^', stroke{KEY_1 + 5, true}},
+	{"ampersand", '&', stroke{KEY_1 + 6, true}},
+	{"asterisk", '*', stroke{KEY_1 + 7, true}},
+	{"parenleft", '(', stroke{KEY_1 + 8, true}},
+	{"parenright", ')', stroke{KEY_0, true}},
+}
+
+// Клавиши без символа. Модификаторы названы и как в xdotool (ctrl, alt,
+// super), и как keysym.
+var namedKeys = map[string]uint16{
+	"ctrl": KEY_LEFTCTRL, "control": KEY_LEFTCTRL, "control_l": KEY_LEFTCTRL, "control_r": KEY_RIGHTCTRL,
+	"shift": KEY_LEFTSHIFT, "shift_l": KEY_LEFTSHIFT, "shift_r": KEY_RIGHTSHIFT,
+	"alt": KEY_LEFTALT, "alt_l": KEY_LEFTALT, "alt_r": KEY_RIGHTALT, "altgr": KEY_RIGHTALT, "iso_level3_shift": KEY_RIGHTALT,
+	"super": KEY_LEFTMETA, "super_l": KEY_LEFTMETA, "super_r": KEY_RIGHTMETA, "meta": KEY_LEFTMETA, "meta_l": KEY_LEFTMETA, "meta_r": KEY_RIGHTMETA,
+
+	"enter": KEY_ENTER, "kp_enter": KEY_ENTER, "backspace": KEY_BACKSPACE, "escape": KEY_ESC, "esc": KEY_ESC,
+	"delete": KEY_DELETE, "insert": KEY_INSERT, "home": KEY_HOME, "end": KEY_END,
+	"prior": KEY_PAGEUP, "page_up": KEY_PAGEUP, "next": KEY_PAGEDOWN, "page_down": KEY_PAGEDOWN,
+	"up": KEY_UP, "down": KEY_DOWN, "left": KEY_LEFT, "right": KEY_RIGHT,
+	"print": KEY_SYSRQ, "pause": KEY_PAUSE, "menu": KEY_COMPOSE,
+	"caps_lock": KEY_CAPSLOCK, "num_lock": KEY_NUMLOCK, "scroll_lock": KEY_SCROLLLOCK,
+
+	"xf86audiomute": KEY_MUTE, "xf86audiolowervolume": KEY_VOLUMEDOWN, "xf86audioraisevolume": KEY_VOLUMEUP,
+	"xf86audioplay": KEY_PLAYPAUSE, "xf86audiopause": KEY_PAUSECD, "xf86audiostop": KEY_STOPCD,
+	"xf86audionext": KEY_NEXTSONG, "xf86audioprev": KEY_PREVSONG,
+	"xf86monbrightnessup": KEY_BRIGHTNESSUP, "xf86monbrightnessdown": KEY_BRIGHTNESSDOWN,
+}
+
+var (
+	// Символы, которые может напечатать Type
+	chars = make(map[rune]stroke)
+	// Имена keysym для символов, без учёта регистра, кроме одиночных букв
+	symbolNames = make(map[string]stroke)
+)
+
+func init() {
+	for _, row := range letterRows {
+		for i, c := range row.letters {
+			code := row.first + uint16(i)
+			chars[c] = stroke{code, false}
+			chars[c-'a'+'A'] = stroke{code, true}
+		}
+	}
+	// KEY_0 идёт сразу после KEY_9
+	for i, c := range "1234567890" {
+		chars[c] = stroke{uint16(KEY_1 + i), false}
+	}
+	for _, s := range symbols {
+		chars[s.char] = s.stroke
+		symbolNames[strings.ToLower(s.name)] = s.stroke
+	}
+	for i := 0; i < 24; i++ {
+		code := uint16(KEY_F1 + i)
+		switch {
+		case i >= 12:
+			code = uint16(KEY_F13 + i - 12)
+		case i >= 10:
+			code = uint16(KEY_F11 + i - 10)
+		}
+		namedKeys[fmt.Sprintf("f%d", i+1)] = code
+	}
+}
+
+// Combo — клавиши, нажимаемые вместе. Нажимаются по порядку,
+// отпускаются в обратном.
+type Combo []uint16
+
+// ParseKeys разбирает последовательность в синтаксисе xdotool key:
+// комбинации через пробел, клавиши в комбинации через «+», например
+// "ctrl+shift+t Return". Имена — keysym из X11 ("BackSpace", "Prior",
+// "XF86AudioMute") и модификаторы ctrl, shift, alt, super; регистр в них
+// не важен. Одиночный символ нажимается так, как он печатается: "A" — это
+// shift+a, "at" и "@" — shift+2 в раскладке US.
+func ParseKeys(s string) ([]Combo, error) {
+	var result []Combo
+	for _, field := range strings.Fields(s) {
+		var combo Combo
+		for _, name := range strings.Split(field, "+") {
+			key, err := lookupKey(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field, err)
+			}
+			if key.shift {
+				combo = combo.with(KEY_LEFTSHIFT)
+			}
+			combo = combo.with(key.code)
+		}
+		result = append(result, combo)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("не заданы клавиши")
+	}
+	return result, nil
+}
+
+// TextCombos возвращает нажатия, которые печатают text в раскладке US
+func TextCombos(text string) ([]Combo, error) {
+	result := make([]Combo, 0, len(text))
+	for _, c := range text {
+		key, ok := chars[c]
+		if !ok {
+			return nil, fmt.Errorf("символ %q нельзя напечатать", c)
+		}
+		combo := Combo{key.code}
+		if key.shift {
+			combo = Combo{KEY_LEFTSHIFT, key.code}
+		}
+		result = append(result, combo)
+	}
+	return result, nil
+}
+
+func lookupKey(name string) (stroke, error) {
+	if name == "" {
+		return stroke{}, fmt.Errorf("пустое имя клавиши")
+	}
+	if runes := []rune(name); len(runes) == 1 {
+		if key, ok := chars[runes[0]]; ok {
+			return key, nil
+		}
+	}
+	lower := strings.ToLower(name)
+	if code, ok := namedKeys[lower]; ok {
+		return stroke{code, false}, nil
+	}
+	if key, ok := symbolNames[lower]; ok {
+		return key, nil
+	}
+	return stroke{}, fmt.Errorf("неизвестная клавиша %q", name)
+}
+
+func (c Combo) with(code uint16) Combo {
+	for _, existing := range c {
+		if existing == code {
+			return c
+		}
+	}
+	return append(c, code)
+}
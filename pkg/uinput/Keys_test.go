@@ -0,0 +1,77 @@
+package uinput
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		keys string
+		want []Combo
+	}{
+		{"ctrl+c", []Combo{{KEY_LEFTCTRL, 46}}},
+		{"ctrl+shift+t Return", []Combo{{KEY_LEFTCTRL, KEY_LEFTSHIFT, 20}, {KEY_ENTER}}},
+		{"Control_L+Alt_L+Delete", []Combo{{KEY_LEFTCTRL, KEY_LEFTALT, KEY_DELETE}}},
+		{"super+Left", []Combo{{KEY_LEFTMETA, KEY_LEFT}}},
+		// Регистр в именах не важен, кроме одиночных букв
+		{"BackSpace backspace", []Combo{{KEY_BACKSPACE}, {KEY_BACKSPACE}}},
+		{"a A", []Combo{{30}, {KEY_LEFTSHIFT, 30}}},
+		{"at @", []Combo{{KEY_LEFTSHIFT, KEY_1 + 1}, {KEY_LEFTSHIFT, KEY_1 + 1}}},
+		// Shift не повторяется, если он уже в комбинации
+		{"shift+A", []Combo{{KEY_LEFTSHIFT, 30}}},
+		{"F1 F12 F13 F24", []Combo{{KEY_F1}, {KEY_F12}, {KEY_F13}, {KEY_F13 + 11}}},
+		{"Prior Next", []Combo{{KEY_PAGEUP}, {KEY_PAGEDOWN}}},
+		{"XF86AudioMute XF86AudioRaiseVolume", []Combo{{KEY_MUTE}, {KEY_VOLUMEUP}}},
+		{"  ctrl+v   Tab ", []Combo{{KEY_LEFTCTRL, 47}, {KEY_TAB}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			got, err := ParseKeys(tt.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseKeys = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeysErrors(t *testing.T) {
+	tests := []struct {
+		keys string
+		err  string
+	}{
+		{"", "не заданы клавиши"},
+		{"   ", "не заданы клавиши"},
+		{"ctrl+", "пустое имя клавиши"},
+		{"ctrl+nokey", `неизвестная клавиша "nokey"`},
+		{"ctrl+c ж", `неизвестная клавиша "ж"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			_, err := ParseKeys(tt.keys)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ошибка %v, ожидалось %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTextCombos(t *testing.T) {
+	got, err := TextCombos("Hi, 1!\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Combo{
+		{KEY_LEFTSHIFT, 35}, {23}, {KEY_COMMA}, {KEY_SPACE}, {KEY_1}, {KEY_LEFTSHIFT, KEY_1}, {KEY_ENTER},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TextCombos = %v, ожидалось %v", got, want)
+	}
+	if _, err := TextCombos("привет"); err == nil {
+		t.Error("кириллица не должна печататься")
+	}
+}
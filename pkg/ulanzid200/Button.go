@@ -2,5 +2,23 @@ package ulanzid200
 
 type Button struct {
 	Name string
+	// Имя файла в папке иконок или абсолютный путь
 	Icon string
-}
\ No newline at end of file
+	// Кнопка с несколькими состояниями: пульт показывает Views[State].
+	// Если Views пуст, единственный вид задают Name и Icon.
+	State int
+	Views []ButtonView
+}
+
+type ButtonView struct {
+	Name string
+	Icon string
+}
+
+// GetViews возвращает виды кнопки по порядку состояний
+func (b *Button) GetViews() []ButtonView {
+	if len(b.Views) == 0 {
+		return []ButtonView{{Name: b.Name, Icon: b.Icon}}
+	}
+	return b.Views
+}
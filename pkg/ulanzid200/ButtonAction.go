@@ -1,9 +1,10 @@
 package ulanzid200
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
 )
 
 type ButtonAction struct {
@@ -12,11 +13,7 @@ type ButtonAction struct {
 	State   byte
 }
 
-type DeviceInfo struct {
-	Dversion     string `json:"Dversion"`
-	SerialNumber string `json:"SerialNumber"`
-	Error        string `json:"error"`
-}
+type DeviceInfo = codec.DeviceInfo
 
 func ParseInput(dev *UlanziD200Device, inp []byte) (action *ButtonAction, info *DeviceInfo, err error) {
 	parsed, err := ParseIncomingStruct(inp)
@@ -30,13 +27,11 @@ func ParseInput(dev *UlanziD200Device, inp []byte) (action *ButtonAction, info *
 
 	switch parsed.CommandProtocol {
 	case IN_DEVICE_INFO:
-		var info DeviceInfo
-		err := json.Unmarshal([]byte(parsed.Data.(string)), &info)
-		if err != nil {
-			fmt.Println("Ошибка парсинга:", err)
-			return nil, nil, err
+		info, ok := parsed.Data.(*DeviceInfo)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected data type for device info: %T", parsed.Data)
 		}
-		return nil, &info, err
+		return nil, info, nil
 
 	case IN_BUTTON:
 		dev.lastActionTime = time.Now()
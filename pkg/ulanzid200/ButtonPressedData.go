@@ -1,22 +1,14 @@
 package ulanzid200
 
-import "fmt"
+import "github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
 
-type ButtonPressedData struct {
-	State   byte
-	Index   byte
-	Pressed bool
-}
+type ButtonPressedData = codec.ButtonPressed
 
-// Разбор данных кнопки из 3 байтов: state, index, const(0x01)
+// Разбор данных кнопки из 4 байтов: state, index, const(0x01), pressed
 func ParseButtonPressed(data []byte) (*ButtonPressedData, error) {
-	if len(data) < 4 || data[2] != 0x01 {
-		return nil, fmt.Errorf("неверный формат ButtonPressedStruct")
+	msg, err := codec.UnmarshalPayload(codec.IN_BUTTON, data)
+	if err != nil {
+		return nil, err
 	}
-	pressed := data[3] == 0x01
-	return &ButtonPressedData{
-		State:   data[0],
-		Index:   data[1],
-		Pressed: pressed,
-	}, nil
+	return msg.(*ButtonPressedData), nil
 }
@@ -0,0 +1,320 @@
+package ulanzid200
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type DeviceEventType int
+
+const (
+	DeviceAdded DeviceEventType = iota
+	DeviceRemoved
+)
+
+// DeviceEvent сообщает о появлении пульта (после получения DeviceInfo)
+// или о его отключении
+type DeviceEvent struct {
+	Type   DeviceEventType
+	Serial string
+	Device *UlanziD200Device
+}
+
+// DeviceManager находит все подключённые D200 и для каждого запускает
+// отдельный UlanziD200Device
+type DeviceManager struct {
+	transport    Transport
+	mode         SmallWindowMode
+	iconPath     string
+	tmpPath      string
+	scanInterval time.Duration
+	hotplug      HotplugSource
+
+	mu        sync.Mutex
+	devices   map[string]*UlanziD200Device // по пути HID
+	announced map[*UlanziD200Device]string // серийные номера объявленных устройств
+	eventChan chan *DeviceEvent
+	stopped   bool
+	// Закрывается в Stop, чтобы события не ждали читателя, которого уже нет
+	stop chan struct{}
+	// Сигнал watchHotplug о пульте, потерявшем связь
+	lostChan chan struct{}
+}
+
+func NewDeviceManager(transport Transport, mode SmallWindowMode, IconPath, TmpPath string) *DeviceManager {
+	return &DeviceManager{
+		transport:    transport,
+		mode:         mode,
+		iconPath:     IconPath,
+		tmpPath:      TmpPath,
+		scanInterval: 3 * time.Second,
+		devices:      make(map[string]*UlanziD200Device),
+		announced:    make(map[*UlanziD200Device]string),
+		eventChan:    make(chan *DeviceEvent, 16),
+		stop:         make(chan struct{}),
+		lostChan:     make(chan struct{}, 1),
+	}
+}
+
+func (m *DeviceManager) DeviceEventChan() chan *DeviceEvent {
+	return m.eventChan
+}
+
+// SetScanInterval задаёт период опроса списка HID-устройств
+func (m *DeviceManager) SetScanInterval(interval time.Duration) {
+	m.scanInterval = interval
+}
+
+// SetHotplugSource включает пересканирование по событиям подключения
+// вместо периодического опроса
+func (m *DeviceManager) SetHotplugSource(source HotplugSource) {
+	m.hotplug = source
+}
+
+// Devices возвращает пульты, приславшие DeviceInfo, упорядоченные по серийному номеру
+func (m *DeviceManager) Devices() []*UlanziD200Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*UlanziD200Device, 0, len(m.announced))
+	for dev := range m.announced {
+		result = append(result, dev)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return m.announced[result[i]] < m.announced[result[j]]
+	})
+	return result
+}
+
+// Device возвращает пульт по серийному номеру
+func (m *DeviceManager) Device(serial string) *UlanziD200Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dev, s := range m.announced {
+		if s == serial {
+			return dev
+		}
+	}
+	return nil
+}
+
+func (m *DeviceManager) Start() {
+	if m.hotplug != nil {
+		go m.watchHotplug()
+		return
+	}
+	go m.poll()
+}
+
+// poll периодически сканирует устройства до остановки менеджера
+func (m *DeviceManager) poll() {
+	for {
+		m.Scan()
+		time.Sleep(m.scanInterval)
+		if m.isStopped() {
+			break
+		}
+	}
+}
+
+// Задержки повторного сканирования после подключения: HID-интерфейс
+// появляется чуть позже, чем USB-устройство
+var hotplugRescanDelays = []time.Duration{0, 300 * time.Millisecond, time.Second, 3 * time.Second}
+
+// Первая задержка повторного открытия пульта, потерявшего связь без
+// отключения. Дальше она удваивается, но не превышает scanInterval.
+const lostRescanDelay = 300 * time.Millisecond
+
+func (m *DeviceManager) watchHotplug() {
+	m.Scan()
+	var rescan <-chan time.Time
+	var pending []time.Duration
+	// Задержка следующей попытки открыть потерянный пульт, 0 — таких нет
+	var lostDelay time.Duration
+	for {
+		select {
+		case event, ok := <-m.hotplug.Events():
+			if !ok {
+				if m.isStopped() {
+					return
+				}
+				fmt.Println("Hotplug недоступен, используется опрос устройств")
+				m.poll()
+				return
+			}
+			fmt.Printf("Hotplug: %v %s\n", event.Type, event.DevPath)
+			m.Scan()
+			if event.Type == Connected {
+				pending = append([]time.Duration(nil), hotplugRescanDelays[1:]...)
+			}
+		case <-m.lostChan:
+			// Без события отключения никто больше не пересканирует
+			// список, поэтому пробуем открыть пульт заново
+			if lostDelay == 0 {
+				lostDelay = lostRescanDelay
+			}
+		case <-rescan:
+			m.Scan()
+		}
+		if m.isStopped() {
+			return
+		}
+		rescan = nil
+		known, unopened := m.unopened()
+		if len(pending) > 0 && (unopened || known == 0) {
+			rescan = time.After(pending[0])
+			pending = pending[1:]
+			continue
+		}
+		pending = nil
+		if lostDelay > 0 && unopened {
+			rescan = time.After(lostDelay)
+			lostDelay = min(2*lostDelay, max(m.scanInterval, lostRescanDelay))
+		} else {
+			lostDelay = 0
+		}
+	}
+}
+
+// unopened возвращает число подключённых пультов и есть ли среди них
+// ещё не открытые
+func (m *DeviceManager) unopened() (known int, unopened bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, info := range m.transport.Enumerate() {
+		if info.IsD200() {
+			known++
+			if _, ok := m.devices[info.Path]; !ok {
+				unopened = true
+			}
+		}
+	}
+	return known, unopened
+}
+
+func (m *DeviceManager) Stop() {
+	m.mu.Lock()
+	if !m.stopped {
+		close(m.stop)
+	}
+	m.stopped = true
+	devices := make([]*UlanziD200Device, 0, len(m.devices))
+	for _, dev := range m.devices {
+		devices = append(devices, dev)
+	}
+	m.mu.Unlock()
+
+	for _, dev := range devices {
+		dev.Stop()
+		m.remove(dev)
+	}
+	if m.hotplug != nil {
+		m.hotplug.Close()
+	}
+}
+
+func (m *DeviceManager) isStopped() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopped
+}
+
+// Scan сверяет список подключённых пультов с запущенными устройствами
+func (m *DeviceManager) Scan() {
+	if !m.transport.Supported() {
+		fmt.Println("HID not supported")
+		return
+	}
+
+	present := make(map[string]HIDInfo)
+	for _, info := range m.transport.Enumerate() {
+		if info.IsD200() {
+			present[info.Path] = info
+		}
+	}
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	var gone []*UlanziD200Device
+	for path, dev := range m.devices {
+		if _, ok := present[path]; !ok {
+			gone = append(gone, dev)
+		}
+	}
+	var added []*UlanziD200Device
+	for path, info := range present {
+		if _, ok := m.devices[path]; ok {
+			continue
+		}
+		dev := newBoundDevice(m.transport, info, m.mode, m.iconPath, m.tmpPath)
+		dev.onInfo = m.announce
+		dev.onDisconnect = m.lost
+		m.devices[path] = dev
+		added = append(added, dev)
+	}
+	m.mu.Unlock()
+
+	for _, dev := range gone {
+		fmt.Printf("Пульт отключён: %s\n", dev.Serial())
+		dev.Stop()
+		m.remove(dev)
+	}
+	for _, dev := range added {
+		fmt.Printf("Найден пульт: %s\n", dev.hidInfo.Path)
+		dev.Start()
+	}
+}
+
+// announce вызывается устройством при получении DeviceInfo
+func (m *DeviceManager) announce(dev *UlanziD200Device) {
+	serial := dev.Serial()
+	m.mu.Lock()
+	if _, ok := m.announced[dev]; ok || m.devices[dev.hidInfo.Path] != dev {
+		m.mu.Unlock()
+		return
+	}
+	m.announced[dev] = serial
+	m.mu.Unlock()
+
+	fmt.Printf("Пульт готов: %s\n", serial)
+	m.sendEvent(&DeviceEvent{Type: DeviceAdded, Serial: serial, Device: dev})
+}
+
+// lost вызывается устройством, потерявшим связь. Пульт при этом может
+// остаться в системе (ошибка чтения без отключения), поэтому в режиме
+// hotplug его нужно открыть заново.
+func (m *DeviceManager) lost(dev *UlanziD200Device) {
+	m.remove(dev)
+	select {
+	case m.lostChan <- struct{}{}:
+	default:
+	}
+}
+
+// remove забывает устройство и, если оно было объявлено, сообщает об отключении
+func (m *DeviceManager) remove(dev *UlanziD200Device) {
+	m.mu.Lock()
+	if m.devices[dev.hidInfo.Path] == dev {
+		delete(m.devices, dev.hidInfo.Path)
+	}
+	serial, ok := m.announced[dev]
+	delete(m.announced, dev)
+	m.mu.Unlock()
+
+	if ok {
+		m.sendEvent(&DeviceEvent{Type: DeviceRemoved, Serial: serial, Device: dev})
+	}
+}
+
+// sendEvent ждёт места в очереди событий, пока менеджер не остановлен.
+// Вызывается без m.mu, чтобы медленный читатель не блокировал Scan и Stop.
+func (m *DeviceManager) sendEvent(event *DeviceEvent) {
+	select {
+	case m.eventChan <- event:
+	case <-m.stop:
+	}
+}
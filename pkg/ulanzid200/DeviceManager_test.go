@@ -0,0 +1,138 @@
+package ulanzid200
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func fakeDeck(n int) HIDInfo {
+	return HIDInfo{
+		Path:      fmt.Sprintf("fake:%d", n),
+		VendorID:  VendorID,
+		ProductID: ProductID,
+		Serial:    fmt.Sprintf("FAKE%04d", n),
+	}
+}
+
+func TestDeviceManagerStopWithUnreadEvents(t *testing.T) {
+	// Событий больше, чем вмещает очередь, и их никто не читает
+	const count = 20
+	devices := make([]HIDInfo, count)
+	for i := range devices {
+		devices[i] = fakeDeck(i)
+	}
+	transport := NewFakeTransport(devices...)
+	m := NewDeviceManager(transport, CLOCK, t.TempDir(), t.TempDir())
+	m.Scan()
+	for i := 0; i < count; i++ {
+		conn := transport.WaitConnection(i, 2*time.Second)
+		if conn == nil {
+			t.Fatalf("пульт %d не подключился", i)
+		}
+		conn.InjectDeviceInfo(DeviceInfo{SerialNumber: conn.Info.Serial})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(m.eventChan) < cap(m.eventChan) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		m.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop заблокирован переполненной очередью событий")
+	}
+}
+
+// waitEvent ждёт следующее событие менеджера
+func waitEvent(t *testing.T, m *DeviceManager, eventType DeviceEventType) *DeviceEvent {
+	t.Helper()
+	select {
+	case event := <-m.DeviceEventChan():
+		if event.Type != eventType {
+			t.Fatalf("событие %v, ожидалось %v", event.Type, eventType)
+		}
+		return event
+	case <-time.After(3 * time.Second):
+		t.Fatalf("нет события %v", eventType)
+	}
+	return nil
+}
+
+// connect ждёт n-е соединение и отвечает на него DeviceInfo
+func connect(t *testing.T, m *DeviceManager, transport *FakeTransport, n int) *FakeConnection {
+	t.Helper()
+	conn := transport.WaitConnection(n, 3*time.Second)
+	if conn == nil {
+		t.Fatalf("нет соединения %d", n)
+	}
+	conn.InjectDeviceInfo(DeviceInfo{SerialNumber: conn.Info.Serial})
+	event := waitEvent(t, m, DeviceAdded)
+	if event.Serial != conn.Info.Serial {
+		t.Fatalf("пульт %q, ожидался %q", event.Serial, conn.Info.Serial)
+	}
+	select {
+	case <-event.Device.RefreshChan():
+	case <-time.After(3 * time.Second):
+		t.Fatal("нет запроса на обновление кнопок")
+	}
+	return conn
+}
+
+func TestDeviceManagerReopensLostDevice(t *testing.T) {
+	transport := NewFakeTransport()
+	hotplug := NewChanHotplugSource()
+	m := NewDeviceManager(transport, CLOCK, t.TempDir(), t.TempDir())
+	m.SetHotplugSource(hotplug)
+	m.SetScanInterval(100 * time.Millisecond)
+	m.Start()
+	defer m.Stop()
+
+	conn := connect(t, m, transport, 0)
+	// Ошибка чтения без события отключения: пульт остаётся в системе
+	for i := 1; i <= 3; i++ {
+		conn.Close()
+		waitEvent(t, m, DeviceRemoved)
+		conn = connect(t, m, transport, i)
+	}
+}
+
+func TestDeviceManagerDoesNotReopenUnpluggedDevice(t *testing.T) {
+	transport := NewFakeTransport()
+	hotplug := NewChanHotplugSource()
+	m := NewDeviceManager(transport, CLOCK, t.TempDir(), t.TempDir())
+	m.SetHotplugSource(hotplug)
+	m.SetScanInterval(20 * time.Millisecond)
+	m.Start()
+	defer m.Stop()
+
+	conn := connect(t, m, transport, 0)
+	transport.SetDevices()
+	conn.Close()
+	waitEvent(t, m, DeviceRemoved)
+	time.Sleep(200 * time.Millisecond)
+	if n := len(transport.Connections()); n != 1 {
+		t.Errorf("соединений: %d", n)
+	}
+}
+
+func TestDeviceManagerPollsWhenHotplugCloses(t *testing.T) {
+	transport := NewFakeTransport()
+	transport.SetDevices()
+	hotplug := NewChanHotplugSource()
+	m := NewDeviceManager(transport, CLOCK, t.TempDir(), t.TempDir())
+	m.SetHotplugSource(hotplug)
+	m.SetScanInterval(20 * time.Millisecond)
+	m.Start()
+	defer m.Stop()
+
+	hotplug.Close()
+	// Пульт подключён без события: его находит только опрос
+	transport.SetDevices(fakeDeck(1))
+	connect(t, m, transport, 0)
+}
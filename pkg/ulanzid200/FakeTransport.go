@@ -0,0 +1,198 @@
+package ulanzid200
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+var ErrFakeClosed = errors.New("fake connection closed")
+
+// FakeTransport — транспорт в памяти для работы без реального пульта.
+// Запоминает все открытые соединения, а через FakeConnection можно
+// подсовывать входящие пакеты и читать исходящие.
+type FakeTransport struct {
+	mu          sync.Mutex
+	devices     []HIDInfo
+	connections []*FakeConnection
+	OpenError   error
+}
+
+func NewFakeTransport(devices ...HIDInfo) *FakeTransport {
+	if len(devices) == 0 {
+		devices = []HIDInfo{{
+			Path:      "fake:0",
+			VendorID:  VendorID,
+			ProductID: ProductID,
+			Serial:    "FAKE0001",
+			Product:   "Fake D200",
+		}}
+	}
+	return &FakeTransport{devices: devices}
+}
+
+func (t *FakeTransport) Supported() bool {
+	return true
+}
+
+func (t *FakeTransport) Enumerate() []HIDInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]HIDInfo(nil), t.devices...)
+}
+
+func (t *FakeTransport) Open(info HIDInfo) (Connection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.OpenError != nil {
+		return nil, t.OpenError
+	}
+	conn := newFakeConnection(info)
+	t.connections = append(t.connections, conn)
+	return conn, nil
+}
+
+// SetDevices заменяет список «подключённых» устройств (эмуляция hotplug)
+func (t *FakeTransport) SetDevices(devices ...HIDInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.devices = devices
+}
+
+func (t *FakeTransport) Connections() []*FakeConnection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*FakeConnection(nil), t.connections...)
+}
+
+// WaitConnection ждёт n-е (с нуля) открытое соединение
+func (t *FakeTransport) WaitConnection(n int, timeout time.Duration) *FakeConnection {
+	deadline := time.Now().Add(timeout)
+	for {
+		conns := t.Connections()
+		if len(conns) > n {
+			return conns[n]
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type FakeConnection struct {
+	Info HIDInfo
+
+	mu        sync.Mutex
+	written   [][]byte
+	notify    chan struct{}
+	incoming  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeConnection(info HIDInfo) *FakeConnection {
+	return &FakeConnection{
+		Info:     info,
+		notify:   make(chan struct{}),
+		incoming: make(chan []byte, 64),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (c *FakeConnection) Read(b []byte) (int, error) {
+	select {
+	case packet := <-c.incoming:
+		return copy(b, packet), nil
+	case <-c.closed:
+		return 0, ErrFakeClosed
+	}
+}
+
+func (c *FakeConnection) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, ErrFakeClosed
+	default:
+	}
+	c.mu.Lock()
+	c.written = append(c.written, append([]byte(nil), b...))
+	close(c.notify)
+	c.notify = make(chan struct{})
+	c.mu.Unlock()
+	return len(b), nil
+}
+
+func (c *FakeConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *FakeConnection) Closed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Written возвращает копию всех записанных пакетов
+func (c *FakeConnection) Written() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.written...)
+}
+
+// WaitWritten ждёт, пока будет записано не меньше n пакетов
+func (c *FakeConnection) WaitWritten(n int, timeout time.Duration) ([][]byte, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		if len(c.written) >= n {
+			written := append([][]byte(nil), c.written...)
+			c.mu.Unlock()
+			return written, true
+		}
+		notify := c.notify
+		c.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return c.Written(), false
+		}
+	}
+}
+
+// Inject подкладывает сырой входящий пакет
+func (c *FakeConnection) Inject(packet []byte) {
+	c.incoming <- append([]byte(nil), packet...)
+}
+
+// InjectMessage кодирует сообщение и подкладывает получившиеся пакеты
+func (c *FakeConnection) InjectMessage(msg codec.Message) error {
+	packets, err := codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	for _, packet := range packets {
+		c.Inject(packet)
+	}
+	return nil
+}
+
+// InjectButton подкладывает пакет IN_BUTTON
+func (c *FakeConnection) InjectButton(index byte, pressed bool) {
+	c.InjectMessage(&ButtonPressedData{Index: index, Pressed: pressed})
+}
+
+// InjectDeviceInfo подкладывает пакет IN_DEVICE_INFO
+func (c *FakeConnection) InjectDeviceInfo(info DeviceInfo) {
+	c.InjectMessage(&info)
+}
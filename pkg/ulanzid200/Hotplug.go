@@ -0,0 +1,126 @@
+package ulanzid200
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type HotplugEventType int
+
+const (
+	Connected HotplugEventType = iota
+	Disconnected
+)
+
+func (t HotplugEventType) String() string {
+	if t == Connected {
+		return "Connected"
+	}
+	return "Disconnected"
+}
+
+// HotplugEvent — подключение или отключение USB-устройства
+type HotplugEvent struct {
+	Type      HotplugEventType
+	DevPath   string
+	VendorID  uint16
+	ProductID uint16
+}
+
+// HotplugSource — источник событий подключения устройств.
+// Events закрывается после Close.
+type HotplugSource interface {
+	Events() <-chan *HotplugEvent
+	Close() error
+}
+
+// ParseUevent разбирает сообщение ядра вида "add@/devices/...\0KEY=VALUE\0..."
+func ParseUevent(msg []byte) map[string]string {
+	env := make(map[string]string)
+	for _, field := range strings.Split(string(msg), "\x00") {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// HotplugEventFromUevent превращает uevent USB-устройства в HotplugEvent.
+// Возвращает nil для событий, не относящихся к подключению USB-устройств.
+func HotplugEventFromUevent(env map[string]string) *HotplugEvent {
+	if env["SUBSYSTEM"] != "usb" || env["DEVTYPE"] != "usb_device" {
+		return nil
+	}
+	var eventType HotplugEventType
+	switch env["ACTION"] {
+	case "add":
+		eventType = Connected
+	case "remove":
+		eventType = Disconnected
+	default:
+		return nil
+	}
+	// PRODUCT=vid/pid/bcdDevice в шестнадцатеричном виде без ведущих нулей
+	parts := strings.Split(env["PRODUCT"], "/")
+	if len(parts) < 2 {
+		return nil
+	}
+	vid, err := strconv.ParseUint(parts[0], 16, 16)
+	if err != nil {
+		return nil
+	}
+	pid, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil
+	}
+	return &HotplugEvent{
+		Type:      eventType,
+		DevPath:   env["DEVPATH"],
+		VendorID:  uint16(vid),
+		ProductID: uint16(pid),
+	}
+}
+
+// ChanHotplugSource — источник событий, которые подаются вручную через Emit.
+// Подходит для тестов и эмулятора.
+type ChanHotplugSource struct {
+	mu     sync.Mutex
+	events chan *HotplugEvent
+	closed bool
+}
+
+func NewChanHotplugSource() *ChanHotplugSource {
+	return &ChanHotplugSource{events: make(chan *HotplugEvent, 16)}
+}
+
+func (s *ChanHotplugSource) Events() <-chan *HotplugEvent {
+	return s.events
+}
+
+// Emit ставит событие в очередь. Если очередь полна, например когда события
+// никто не читает, событие отбрасывается: Emit не блокируется и не держит
+// блокировку, которая нужна Close.
+func (s *ChanHotplugSource) Emit(event *HotplugEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		fmt.Printf("Очередь hotplug переполнена, событие %v %s отброшено\n", event.Type, event.DevPath)
+	}
+}
+
+func (s *ChanHotplugSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package ulanzid200
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlinkHotplugSource слушает uevent'ы ядра через netlink-сокет
+// и отдаёт события подключения пультов D200
+type netlinkHotplugSource struct {
+	fd     int
+	events chan *HotplugEvent
+	// Закрывается в Close и прерывает ожидание, пока события никто не читает
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNetlinkHotplugSource открывает сокет NETLINK_KOBJECT_UEVENT
+func NewNetlinkHotplugSource() (HotplugSource, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть netlink-сокет: %w", err)
+	}
+	// Группа 1 — события, которые рассылает ядро
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("не удалось подписаться на uevent: %w", err)
+	}
+	// Таймаут чтения, чтобы Close мог завершить горутину
+	timeout := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("не удалось задать таймаут netlink-сокета: %w", err)
+	}
+
+	s := &netlinkHotplugSource{
+		fd:     fd,
+		events: make(chan *HotplugEvent, 16),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *netlinkHotplugSource) Events() <-chan *HotplugEvent {
+	return s.events
+}
+
+func (s *netlinkHotplugSource) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *netlinkHotplugSource) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *netlinkHotplugSource) run() {
+	defer close(s.events)
+	defer unix.Close(s.fd)
+
+	buf := make([]byte, 16*1024)
+	for !s.stopped() {
+		n, _, err := unix.Recvfrom(s.fd, buf, 0)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EWOULDBLOCK || err == unix.EINTR {
+				continue
+			}
+			fmt.Println("Ошибка чтения uevent:", err)
+			return
+		}
+		event := HotplugEventFromUevent(ParseUevent(buf[:n]))
+		if event == nil || event.VendorID != VendorID || event.ProductID != ProductID {
+			continue
+		}
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
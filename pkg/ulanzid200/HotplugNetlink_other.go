@@ -0,0 +1,10 @@
+//go:build !linux
+
+package ulanzid200
+
+import "errors"
+
+// NewNetlinkHotplugSource доступен только в Linux
+func NewNetlinkHotplugSource() (HotplugSource, error) {
+	return nil, errors.New("netlink hotplug поддерживается только в Linux")
+}
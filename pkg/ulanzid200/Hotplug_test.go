@@ -0,0 +1,33 @@
+package ulanzid200
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChanHotplugSourceEmitWithoutReader(t *testing.T) {
+	s := NewChanHotplugSource()
+	done := make(chan struct{})
+	go func() {
+		// Событий больше, чем вмещает очередь, и их никто не читает
+		for i := 0; i < 2*cap(s.events); i++ {
+			s.Emit(&HotplugEvent{Type: Connected, DevPath: "/fake"})
+		}
+		s.Close()
+		s.Emit(&HotplugEvent{Type: Disconnected, DevPath: "/fake"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Emit или Close заблокированы полной очередью")
+	}
+
+	count := 0
+	for range s.Events() {
+		count++
+	}
+	if count != cap(s.events) {
+		t.Errorf("событий в очереди: %d, ожидалось %d", count, cap(s.events))
+	}
+}
@@ -0,0 +1,136 @@
+package ulanzid200
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сколько места на диске занимают готовые архивы страниц
+const PageCacheLimit = 32 << 20
+
+// Меняется вместе с форматом архива страницы, чтобы не брать из кэша старые архивы
+const pageCacheVersion = 2
+
+// pageCache хранит собранные архивы страниц в каталоге на диске.
+// Ключ — хэш манифеста и содержимого иконок. Время изменения файла
+// обновляется при каждом обращении, и при превышении PageCacheLimit
+// удаляются давно не использованные архивы.
+type pageCache struct {
+	dir   string
+	limit int64
+	mu    sync.Mutex
+}
+
+var (
+	pageCachesMu sync.Mutex
+	pageCaches   = make(map[string]*pageCache)
+)
+
+// getPageCache возвращает общий для всех пультов кэш в каталоге dir.
+// При первом обращении из каталога удаляется всё лишнее.
+func getPageCache(dir string) *pageCache {
+	pageCachesMu.Lock()
+	defer pageCachesMu.Unlock()
+	if c, ok := pageCaches[dir]; ok {
+		return c
+	}
+	c := &pageCache{dir: dir, limit: PageCacheLimit}
+	c.cleanup()
+	pageCaches[dir] = c
+	return c
+}
+
+func (c *pageCache) path(key string) string {
+	return filepath.Join(c.dir, key+".zip")
+}
+
+func (c *pageCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (c *pageCache) Put(key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return err
+	}
+	path := c.path(key)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write temp file error: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file error: %w", err)
+	}
+	c.prune()
+	return nil
+}
+
+// cleanup удаляет из каталога всё, кроме архивов: каталог page и
+// .build.zip от старой сборки через временные файлы, недописанные .tmp.
+// Затем кэш урезается до лимита.
+func (c *pageCache) cleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".zip") || strings.HasPrefix(name, ".") {
+			if err := os.RemoveAll(filepath.Join(c.dir, name)); err != nil {
+				fmt.Println("Не удалось очистить кэш страниц:", err)
+			}
+		}
+	}
+	c.prune()
+}
+
+// prune удаляет самые старые архивы, пока кэш не уложится в лимит
+func (c *pageCache) prune() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	var infos []os.FileInfo
+	var total int64
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".zip") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= c.limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, info.Name())); err != nil {
+			continue
+		}
+		total -= info.Size()
+	}
+}
@@ -0,0 +1,89 @@
+package ulanzid200
+
+import (
+	"github.com/karalabe/hid"
+)
+
+// HIDInfo описывает найденное HID-устройство независимо от конкретной библиотеки
+type HIDInfo struct {
+	Path         string
+	VendorID     uint16
+	ProductID    uint16
+	Release      uint16
+	Serial       string
+	Manufacturer string
+	Product      string
+	UsagePage    uint16
+	Usage        uint16
+	Interface    int
+}
+
+// Connection — открытое соединение с пультом
+type Connection interface {
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	Close() error
+}
+
+// Transport отвечает за поиск и открытие устройств.
+// Позволяет подменить реальный HID, например, в тестах или эмуляторе.
+type Transport interface {
+	Supported() bool
+	Enumerate() []HIDInfo
+	Open(info HIDInfo) (Connection, error)
+}
+
+// IsD200 проверяет, что найденное устройство — нужный интерфейс Ulanzi D200
+func (info HIDInfo) IsD200() bool {
+	return info.VendorID == VendorID && info.ProductID == ProductID && info.Interface == 0
+}
+
+type hidTransport struct{}
+
+// NewHIDTransport возвращает транспорт поверх karalabe/hid
+func NewHIDTransport() Transport {
+	return hidTransport{}
+}
+
+func (hidTransport) Supported() bool {
+	return hid.Supported()
+}
+
+func (hidTransport) Enumerate() []HIDInfo {
+	hids := hid.Enumerate(0, 0)
+	result := make([]HIDInfo, 0, len(hids))
+	for _, h := range hids {
+		result = append(result, HIDInfo{
+			Path:         h.Path,
+			VendorID:     h.VendorID,
+			ProductID:    h.ProductID,
+			Release:      h.Release,
+			Serial:       h.Serial,
+			Manufacturer: h.Manufacturer,
+			Product:      h.Product,
+			UsagePage:    h.UsagePage,
+			Usage:        h.Usage,
+			Interface:    h.Interface,
+		})
+	}
+	return result
+}
+
+func (hidTransport) Open(info HIDInfo) (Connection, error) {
+	device, err := hid.DeviceInfo{
+		Path:         info.Path,
+		VendorID:     info.VendorID,
+		ProductID:    info.ProductID,
+		Release:      info.Release,
+		Serial:       info.Serial,
+		Manufacturer: info.Manufacturer,
+		Product:      info.Product,
+		UsagePage:    info.UsagePage,
+		Usage:        info.Usage,
+		Interface:    info.Interface,
+	}.Open()
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
@@ -1,25 +1,33 @@
 package ulanzid200
 
 import (
-	"archive/zip"
 	"bytes"
-	"crypto/md5"
-	"encoding/binary"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
-	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/karalabe/hid"
+	"github.com/bjaka-max/dispeys/pkg/icons"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
 )
 
 type UlanziD200Device struct {
-	device           *hid.Device
-	keyPressedChan   chan *KeyPressedEvent
+	transport        Transport
+	device           Connection
+	deviceMu         sync.Mutex
+	reconnectDelay   time.Duration
+	hidInfo          *HIDInfo
+	deviceInfo       *DeviceInfo
+	onInfo           func(*UlanziD200Device)
+	onDisconnect     func(*UlanziD200Device)
+	done             chan struct{}
+	stopOnce         sync.Once
+	keyEventChan     chan *KeyEvent
 	refreshChan      chan struct{}
 	brightness       int
 	labelStyle       LabelStyle
@@ -28,7 +36,10 @@ type UlanziD200Device struct {
 	lastActionTime   time.Time
 	iconPath         string
 	tmpPath          string
-	stopped					 bool
+	// Что сейчас показывает каждая кнопка пульта; nil — неизвестно,
+	// следующий SetButtons отправит полный набор
+	shown            map[int]string
+	buttonsMu        sync.Mutex
 }
 
 const (
@@ -44,16 +55,16 @@ const (
 )
 
 
-type CommandProtocol uint16
+type CommandProtocol = codec.CommandProtocol
 
 const (
-	OUT_SET_BUTTONS              CommandProtocol = 0x0001
-	OUT_PARTIALLY_UPDATE_BUTTONS CommandProtocol = 0x000d
-	OUT_SET_SMALL_WINDOW_DATA    CommandProtocol = 0x0006
-	OUT_SET_BRIGHTNESS           CommandProtocol = 0x000a
-	OUT_SET_LABEL_STYLE          CommandProtocol = 0x000b
-	IN_BUTTON                    CommandProtocol = 0x0101
-	IN_DEVICE_INFO               CommandProtocol = 0x0303
+	OUT_SET_BUTTONS              = codec.OUT_SET_BUTTONS
+	OUT_PARTIALLY_UPDATE_BUTTONS = codec.OUT_PARTIALLY_UPDATE_BUTTONS
+	OUT_SET_SMALL_WINDOW_DATA    = codec.OUT_SET_SMALL_WINDOW_DATA
+	OUT_SET_BRIGHTNESS           = codec.OUT_SET_BRIGHTNESS
+	OUT_SET_LABEL_STYLE          = codec.OUT_SET_LABEL_STYLE
+	IN_BUTTON                    = codec.IN_BUTTON
+	IN_DEVICE_INFO               = codec.IN_DEVICE_INFO
 )
 
 type Packet struct {
@@ -62,24 +73,19 @@ type Packet struct {
 	Data            []byte
 }
 
-type KeyPressedEvent struct {
+// KeyEvent — нажатие или отпускание кнопки
+type KeyEvent struct {
 	Index   int
+	Pressed bool
+	State   byte
+	Time    time.Time
 }
 
-func BuildPacket(cmd CommandProtocol, length int, data []byte) []byte {
-	const PacketSize = 1024
-	var buf bytes.Buffer
-
-	buf.Write([]byte{0x7c, 0x7c}) // header
-
-	binary.Write(&buf, binary.BigEndian, uint16(cmd))
-	binary.Write(&buf, binary.LittleEndian, uint32(length))
+// Индекс «кнопки» маленького окна: нажатие переключает его режим
+const SmallWindowIndex = 13
 
-	padded := make([]byte, PacketSize-8)
-	copy(padded, data)
-	buf.Write(padded)
-
-	return buf.Bytes()
+func BuildPacket(cmd CommandProtocol, length int, data []byte) []byte {
+	return codec.BuildFrame(cmd, length, data)
 }
 
 func EqualJSON(a, b any) bool {
@@ -118,41 +124,91 @@ func (d *UlanziD200Device) SetSmallWindowData(data SmallWindowData, force bool)
 	}
 	d.smallWindowData = data
 
-	packetStr := fmt.Sprintf("%d|%v|%v|%v|%v", data.Mode, data.CPU, data.MEM, data.Time, data.GPU)
-	
-	packetData := []byte(packetStr)
-	packet := BuildPacket(OUT_SET_SMALL_WINDOW_DATA, len(packetData), packetData)
-	d.writePacket(packet)
+	packets, err := codec.Encode(&codec.SmallWindow{
+		Mode: int(data.Mode),
+		CPU:  data.CPU,
+		MEM:  data.MEM,
+		Time: data.Time,
+		GPU:  data.GPU,
+	})
+	if err != nil {
+		fmt.Println("SetSmallWindowData error:", err)
+		return
+	}
+	for _, packet := range packets {
+		d.writePacket(packet)
+	}
 }
 
+// SetButtons показывает кнопки на пульте. Загружаются только кнопки, которые
+// отличаются от уже показанных; без updateOnly кнопки, которых нет в buttons,
+// очищаются. Полный набор отправляется, только если содержимое пульта
+// неизвестно — после подключения.
 func (d *UlanziD200Device) SetButtons(buttons map[int]Button, updateOnly bool) {
-	zipPath := d.prepareZip(buttons)
-	data, _ := os.ReadFile(zipPath)
+	d.buttonsMu.Lock()
+	defer d.buttonsMu.Unlock()
 
-	command := OUT_SET_BUTTONS
-	if updateOnly {
-		command = OUT_PARTIALLY_UPDATE_BUTTONS
+	entries := make(map[int]*buttonEntry)
+	for index, btn := range buttons {
+		entries[index] = d.newButtonEntry(btn)
 	}
 
-	chunkSize := 1024
-	chunk := data[:chunkSize-8]
-	packet := BuildPacket(command, len(data), chunk)
-	d.writePacket(packet)
+	command := OUT_PARTIALLY_UPDATE_BUTTONS
+	switch {
+	case d.shown == nil && !updateOnly:
+		command = OUT_SET_BUTTONS
+		d.shown = make(map[int]string)
+	case d.shown != nil:
+		if !updateOnly {
+			for index := range d.shown {
+				if _, ok := entries[index]; !ok {
+					entries[index] = d.newButtonEntry(Button{})
+				}
+			}
+		}
+		for index, entry := range entries {
+			if d.shown[index] == entry.key {
+				delete(entries, index)
+			}
+		}
+		if len(entries) == 0 {
+			return
+		}
+	}
 
-	for i := chunkSize - 8; i < len(data); i += chunkSize {
-		end := i + chunkSize
-		if end > len(data) {
-			end = len(data)
+	data, err := d.prepareZip(entries)
+	if err != nil {
+		fmt.Println("Ошибка сборки страницы:", err)
+		return
+	}
+	for _, packet := range codec.SplitPayload(command, data) {
+		d.writePacket(packet)
+	}
+	// Частичное обновление до полного набора не запоминается:
+	// остальные кнопки всё равно неизвестны
+	if d.shown != nil {
+		for index, entry := range entries {
+			d.shown[index] = entry.key
 		}
-		chunk := make([]byte, chunkSize)
-		copy(chunk, data[i:end])
-		d.writePacket(chunk)
 	}
 }
 
+// resetShown забывает содержимое пульта, например после переподключения
+func (d *UlanziD200Device) resetShown() {
+	d.buttonsMu.Lock()
+	defer d.buttonsMu.Unlock()
+	d.shown = nil
+}
+
+func (d *UlanziD200Device) getDevice() Connection {
+	d.deviceMu.Lock()
+	defer d.deviceMu.Unlock()
+	return d.device
+}
+
 func (d *UlanziD200Device) writePacket(packet []byte) {
-	if d.device != nil {
-		_, err := d.device.Write(packet)
+	if device := d.getDevice(); device != nil {
+		_, err := device.Write(packet)
 		if err != nil {
 			fmt.Println("writePacket error:", err)
 		}
@@ -160,9 +216,8 @@ func (d *UlanziD200Device) writePacket(packet []byte) {
 }
 
 func (d *UlanziD200Device) readPacket(packet []byte) (n int, err error) {
-	if d.device != nil {
-		var err error
-		n, err = d.device.Read(packet)
+	if device := d.getDevice(); device != nil {
+		n, err = device.Read(packet)
 		if err != nil {
 			err = fmt.Errorf("readPacket error : %w", err)
 		}
@@ -171,225 +226,202 @@ func (d *UlanziD200Device) readPacket(packet []byte) (n int, err error) {
 }
 
 
-var invalidBytes = [][]byte{
-	{0x00},
-	// {0x01},
-	{0x7c},
+// prepareIcon приводит иконку к размеру и формату пульта. Возвращает имя
+// для архива и путь к готовому файлу; если иконку не удалось декодировать,
+// она копируется как есть. Если файл не нашёлся (в том числе иконки theme:
+// и app: в системе), имя пустое.
+func (d *UlanziD200Device) prepareIcon(icon string) (name, src string) {
+	src = icon
+	if icons.IsSystemIcon(icon) {
+		var err error
+		src, err = icons.ResolveIcon(icon)
+		if err != nil {
+			fmt.Println(err)
+			return "", ""
+		}
+	} else if !filepath.IsAbs(icon) {
+		src = filepath.Join(d.iconPath, icon)
+	}
+	normalized, err := icons.Normalize(src, filepath.Join(d.tmpPath, ".icons"))
+	if err != nil {
+		fmt.Println("Ошибка подготовки иконки:", err)
+		return rawIconName(src), src
+	}
+	return filepath.Base(normalized), normalized
 }
 
-func randomString(n int) string {
-	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+// rawIconName — имя иконки, которую не удалось нормализовать: хэш содержимого,
+// чтобы изменённый файл не совпал с тем, что уже на пульте
+func rawIconName(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
 	}
-	return string(b)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])[:32] + filepath.Ext(path)
 }
 
-func containsInvalidByte(b byte) bool {
-	for _, inv := range invalidBytes {
-		if b == inv[0] {
-			return true
+// buttonEntry — запись кнопки в manifest.json и её иконки
+type buttonEntry struct {
+	manifest map[string]interface{}
+	// Имя иконки в архиве -> файл, из которого её взять
+	iconFiles map[string]string
+	// Содержимое записи для сравнения с тем, что уже на пульте.
+	// Имена иконок — хэши содержимого, поэтому изменённая иконка тоже отличается.
+	key string
+}
+
+func (d *UlanziD200Device) newButtonEntry(btn Button) *buttonEntry {
+	views := btn.GetViews()
+	state := btn.State
+	if state < 0 || state >= len(views) {
+		state = 0
+	}
+	entry := &buttonEntry{iconFiles: make(map[string]string)}
+	params := []map[string]string{}
+	for _, view := range views {
+		param := map[string]string{}
+		if view.Name != "" {
+			param["Text"] = view.Name
+		}
+		if view.Icon != "" {
+			if name, src := d.prepareIcon(view.Icon); name != "" {
+				entry.iconFiles[name] = src
+				param["Icon"] = "icons/" + name
+			}
 		}
+		params = append(params, param)
+	}
+	entry.manifest = map[string]interface{}{
+		"State":     state,
+		"ViewParam": params,
 	}
-	return false
+	key, _ := json.Marshal(entry.manifest)
+	entry.key = string(key)
+	return entry
 }
 
-func (d *UlanziD200Device) prepareZip(buttons map[int]Button) string {
-	buildPath := filepath.Join(d.tmpPath, ".build")
-	pagePath := filepath.Join(buildPath, "page")
-	os.RemoveAll(pagePath)
-	os.MkdirAll(filepath.Join(pagePath, "icons"), os.ModePerm)
+// prepareZip собирает архив страницы с manifest.json и иконками кнопок.
+// Архив собирается в памяти, готовые архивы кэшируются в tmpPath/.build
+// по хэшу манифеста и содержимого иконок.
+func (d *UlanziD200Device) prepareZip(entries map[int]*buttonEntry) ([]byte, error) {
 	manifest := make(map[string]interface{})
-	icons := []string{}
+	iconFiles := make(map[string]string)
 
-	for index, btn := range buttons {
+	for index, entry := range entries {
 		row := index / ButtonCols
 		col := index % ButtonCols
-		entry := map[string]interface{}{
-			"State":     0,
-			"ViewParam": []map[string]string{},
-		}
-		param := map[string]string{}
-		if btn.Name != "" {
-			param["Text"] = btn.Name
+		manifest[fmt.Sprintf("%d_%d", col, row)] = entry.manifest
+		for name, src := range entry.iconFiles {
+			iconFiles[name] = src
 		}
-		if btn.Icon != "" {
-			icons = append(icons, btn.Icon)
-			param["Icon"] = "icons/" + btn.Icon
-		}
-		entry["ViewParam"] = []map[string]string{param}
-		manifest[fmt.Sprintf("%d_%d", col, row)] = entry
 	}
 
-	manifestData, _ := json.MarshalIndent(manifest, "", "  ")
-
-	hash := md5.Sum(manifestData)
-	hashHex := hex.EncodeToString(hash[:])
-	zipPath := filepath.Join(buildPath, hashHex + ".zip")
-	
-	_, err := os.Stat(zipPath)
-	if err == nil || !os.IsNotExist(err) {
-		return zipPath
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
 	}
 
-	os.WriteFile(filepath.Join(pagePath, "manifest.json"), manifestData, 0644)
-
-	for _, icon := range icons {
-		src := filepath.Join(d.iconPath, icon)
-		dst := filepath.Join(pagePath, "icons", icon)
-		copyFile(src, dst)
+	names := make([]string, 0, len(iconFiles))
+	for name := range iconFiles {
+		names = append(names, name)
 	}
-
-	dummyPath := filepath.Join(pagePath, "dummy.txt")
-
-	var dummyStr string
-	dummyRetries := 04
-	buildZipPath := filepath.Join(buildPath, ".build.zip")
-
-	for {
-		// Если это не первый заход — создаём dummy-файл
-		if dummyRetries > 0 {
-			fmt.Println("Generating dummy string...")
-			dummyStr += randomString(8 * dummyRetries)
-			err := os.WriteFile(dummyPath, []byte(dummyStr), 0644)
-			if err != nil {
-				panic(err)
-			}
-		}
-
-		// Создаём zip
-		err := ZipFolder(pagePath, buildZipPath)
-		if err != nil {
-			panic(err)
-		}
-
-		// Проверяем файл на наличие запрещённых байт в позициях 1016 + n*1024
-		fileInfo, err := os.Stat(buildZipPath)
+	sort.Strings(names)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d\n", pageCacheVersion)
+	hash.Write(manifestData)
+	files := []codec.ZipFile{{Name: "manifest.json", Data: manifestData}}
+	for _, name := range names {
+		data, err := os.ReadFile(iconFiles[name])
 		if err != nil {
-			panic(err)
-		}
-
-		valid := true
-		f, err := os.Open(buildZipPath)
-		if err != nil {
-			panic(err)
-		}
-
-		for i := int64(1016); i < fileInfo.Size(); i += 1024 {
-			_, err = f.Seek(i, io.SeekStart)
-			if err != nil {
-				panic(err)
-			}
-
-			buf := make([]byte, 1)
-			_, err = f.Read(buf)
-			if err != nil && err != io.EOF {
-				panic(err)
-			}
-
-			if containsInvalidByte(buf[0]) {
-				valid = false
-				break
-			}
-		}
-		f.Close()
-
-		if valid {
-			break
+			return nil, fmt.Errorf("не удалось прочитать иконку: %w", err)
 		}
-
-		dummyRetries++
-		time.Sleep(50 * time.Millisecond)
+		files = append(files, codec.ZipFile{Name: "icons/" + name, Data: data})
+		fmt.Fprintf(hash, "%s %d\n", name, len(data))
+		hash.Write(data)
 	}
+	key := hex.EncodeToString(hash.Sum(nil))[:32]
 
-	if err := os.Rename(buildZipPath, zipPath); err != nil {
-		fmt.Printf("не удалось переименовать %s -> %s: %v", buildZipPath, zipPath, err)
+	cache := getPageCache(filepath.Join(d.tmpPath, ".build"))
+	if data, ok := cache.Get(key); ok {
+		return data, nil
 	}
-
-	fmt.Println("ZIP создан и прошёл проверку!")
-	return zipPath
-}
-
-func ZipFolder(srcDir, zipFile string) error {
-	outFile, err := os.Create(zipFile)
+	data, err := codec.BuildZip(files)
 	if err != nil {
-		return fmt.Errorf("не удалось создать архив: %w", err)
+		return nil, err
 	}
-	defer outFile.Close()
-
-	zipWriter := zip.NewWriter(outFile)
-	defer zipWriter.Close()
-
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		relPath, err := filepath.Rel(srcDir, path)
-		if err != nil {
-			return err
-		}
-
-		if relPath == "." {
-			return nil
-		}
+	if err := cache.Put(key, data); err != nil {
+		fmt.Println("Не удалось сохранить архив:", err)
+	}
+	return data, nil
+}
 
-		header, err := zip.FileInfoHeader(info)
-		if err != nil {
-			return err
-		}
-		header.Name = filepath.ToSlash(relPath)
-		if info.IsDir() {
-			header.Name += "/"
-		} else {
-			header.Method = zip.Deflate
-		}
+func New(mode SmallWindowMode, IconPath, TmpPath string) *UlanziD200Device {
+	return NewWithTransport(NewHIDTransport(), mode, IconPath, TmpPath)
+}
 
-		writer, err := zipWriter.CreateHeader(header)
-		if err != nil {
-			return err
-		}
+func NewWithTransport(transport Transport, mode SmallWindowMode, IconPath, TmpPath string) *UlanziD200Device {
+	return &UlanziD200Device{
+		transport: transport,
+		reconnectDelay: 3 * time.Second,
+		smallWindowMode: mode,
+		iconPath: IconPath,
+		tmpPath: TmpPath,
+		keyEventChan: make(chan *KeyEvent),
+		refreshChan : make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
 
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
+// newBoundDevice создаёт устройство, привязанное к конкретному HID.
+// Такое устройство не переподключается само: этим занимается DeviceManager.
+func newBoundDevice(transport Transport, info HIDInfo, mode SmallWindowMode, IconPath, TmpPath string) *UlanziD200Device {
+	d := NewWithTransport(transport, mode, IconPath, TmpPath)
+	d.hidInfo = &info
+	return d
+}
 
-			_, err = io.Copy(writer, file)
-			if err != nil {
-				return err
-			}
+// Serial возвращает серийный номер из DeviceInfo, а если пульт его ещё
+// не прислал — серийный номер или путь HID
+func (d *UlanziD200Device) Serial() string {
+	d.deviceMu.Lock()
+	defer d.deviceMu.Unlock()
+	if d.deviceInfo != nil && d.deviceInfo.SerialNumber != "" {
+		return d.deviceInfo.SerialNumber
+	}
+	if d.hidInfo != nil {
+		if d.hidInfo.Serial != "" {
+			return d.hidInfo.Serial
 		}
+		return d.hidInfo.Path
+	}
+	return ""
+}
 
-		return nil
-	})
+func (d *UlanziD200Device) DeviceInfo() *DeviceInfo {
+	d.deviceMu.Lock()
+	defer d.deviceMu.Unlock()
+	return d.deviceInfo
 }
 
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	fmt.Println(src, dst)
-	if err != nil {
-		fmt.Println(err)
-		return err
+func (d *UlanziD200Device) setDeviceInfo(info *DeviceInfo) {
+	d.deviceMu.Lock()
+	d.deviceInfo = info
+	d.deviceMu.Unlock()
+	if d.onInfo != nil {
+		d.onInfo(d)
 	}
-	return os.WriteFile(dst, data, 0644)
 }
 
-func New(mode SmallWindowMode, IconPath, TmpPath string) *UlanziD200Device {
-	return &UlanziD200Device{
-		smallWindowMode: mode,
-		iconPath: IconPath,
-		tmpPath: TmpPath,
-		keyPressedChan: make(chan *KeyPressedEvent),
-		refreshChan : make(chan struct{}),
-	}
+// SetReconnectDelay задаёт паузу между попытками переподключения
+func (d *UlanziD200Device) SetReconnectDelay(delay time.Duration) {
+	d.reconnectDelay = delay
 }
 
-func (d *UlanziD200Device) KeyPressedChan() chan *KeyPressedEvent {
-	return d.keyPressedChan
+// KeyEventChan отдаёт нажатия и отпускания кнопок с отметками времени
+func (d *UlanziD200Device) KeyEventChan() chan *KeyEvent {
+	return d.keyEventChan
 }
 
 func (d *UlanziD200Device) RefreshChan() chan struct{} {
@@ -400,34 +432,46 @@ func (d *UlanziD200Device) Start() {
 	d.connectToDevice()
 	go func() {
 		for {
-			if d.device != nil {
+			if d.getDevice() != nil {
 				d.SetSmallWindowData(NewSmallWindowData(map[string]interface{}{}), false)
-				time.Sleep(500*time.Millisecond)
 			}
-			
-			if d.stopped {
+			time.Sleep(500*time.Millisecond)
+
+			if d.isStopped() {
 				break
 			}
 		}
 	}()
 	go func() {
-		defer d.device.Close()
+		defer d.closeDevice()
 		packet := make([]byte, 1024)
 		for {
-			if d.device == nil {
+			if d.getDevice() == nil {
 				if !d.connectToDevice() {
-					time.Sleep(3 * time.Second)
+					if d.hidInfo != nil {
+						// Повторным подключением занимается DeviceManager
+						d.disconnected()
+						break
+					}
+					time.Sleep(d.reconnectDelay)
+					if d.isStopped() {
+						break
+					}
 					continue
 				}
 			}
 			plen, err := d.readPacket(packet)
 
-			if d.stopped {
+			if d.isStopped() {
 				break
 			}
 
 			if err != nil || plen < 8 {
 				fmt.Printf("  Error read packet: %v\n", err)
+				if d.hidInfo != nil {
+					d.disconnected()
+					break
+				}
 				d.connectToDevice()
 				continue
 			}
@@ -438,16 +482,30 @@ func (d *UlanziD200Device) Start() {
 				continue
 			}
 			if info != nil {
-				d.refreshChan <- struct{}{}
+				d.setDeviceInfo(info)
+				// Пульт прислал DeviceInfo после включения: кнопки на нём сброшены
+				d.resetShown()
+				select {
+				case d.refreshChan <- struct{}{}:
+				case <-d.done:
+				}
 				d.SetBrightness(100, true)
 			}
 			if buttonAction != nil {
 				i := int(buttonAction.Index)
-				if buttonAction.Pressed && i == 13 {
-					d.smallWindowMode = GetNextMode(d.smallWindowMode)
-				} else if !buttonAction.Pressed {
-					d.keyPressedChan <- &KeyPressedEvent{
-						Index: i,
+				if i == SmallWindowIndex {
+					if buttonAction.Pressed {
+						d.smallWindowMode = GetNextMode(d.smallWindowMode)
+					}
+				} else {
+					select {
+					case d.keyEventChan <- &KeyEvent{
+						Index:   i,
+						Pressed: buttonAction.Pressed,
+						State:   buttonAction.State,
+						Time:    d.lastActionTime,
+					}:
+					case <-d.done:
 					}
 				}
 			}
@@ -455,18 +513,30 @@ func (d *UlanziD200Device) Start() {
 	}()
 }
 
+func (d *UlanziD200Device) closeDevice() {
+	d.deviceMu.Lock()
+	defer d.deviceMu.Unlock()
+	if d.device != nil {
+		d.device.Close()
+		d.device = nil
+	}
+}
+
 func (d *UlanziD200Device) connectToDevice() bool {
-	if !hid.Supported() {
+	if !d.transport.Supported() {
 		fmt.Println("HID not supported")
 		return false
 	}
 	succcess := false
-	if d.device != nil {
-		d.device.Close()
-		time.Sleep(3 * time.Second)
+	if d.getDevice() != nil {
+		d.closeDevice()
+		time.Sleep(d.reconnectDelay)
 	}
 
-	hids := hid.Enumerate(0, 0)
+	hids := d.transport.Enumerate()
+	if d.hidInfo != nil {
+		hids = filterHIDByPath(hids, d.hidInfo.Path)
+	}
 	for i := 0; i < len(hids); i++ {
 		for j := i + 1; j < len(hids); j++ {
 			if hids[i].Path > hids[j].Path {
@@ -475,7 +545,7 @@ func (d *UlanziD200Device) connectToDevice() bool {
 		}
 	}
 	for i, hid := range hids {
-		if hid.VendorID != VendorID || hid.ProductID != ProductID || hid.Interface != 0 {
+		if !hid.IsD200() {
 			continue
 		}
 		fmt.Printf("HID #%d\n", i)
@@ -489,13 +559,16 @@ func (d *UlanziD200Device) connectToDevice() bool {
 		fmt.Printf("  Usage Page:   %#04x\n", hid.UsagePage)
 		fmt.Printf("  Usage:        %d\n", hid.Usage)
 		fmt.Printf("  Interface:    %d\n", hid.Interface)
-		hidDevice, err := hid.Open()
+		hidDevice, err := d.transport.Open(hid)
 		if err != nil {
 			fmt.Printf("  Error opening device: %v\n", err)
 			continue
 		}
 		fmt.Printf("  Device opened successfully.\n")
+		d.resetShown()
+		d.deviceMu.Lock()
 		d.device = hidDevice
+		d.deviceMu.Unlock()
 		succcess = true
 		break
 	}
@@ -503,6 +576,39 @@ func (d *UlanziD200Device) connectToDevice() bool {
 }
 
 func (d *UlanziD200Device) Stop() {
-	d.stopped = true
+	d.stopOnce.Do(func() {
+		close(d.done)
+	})
+	d.closeDevice()
+}
+
+func (d *UlanziD200Device) disconnected() {
+	d.Stop()
+	if d.onDisconnect != nil {
+		d.onDisconnect(d)
+	}
+}
+
+func (d *UlanziD200Device) isStopped() bool {
+	select {
+	case <-d.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done закрывается после остановки устройства
+func (d *UlanziD200Device) Done() <-chan struct{} {
+	return d.done
+}
+
+func filterHIDByPath(hids []HIDInfo, path string) []HIDInfo {
+	for _, h := range hids {
+		if h.Path == path {
+			return []HIDInfo{h}
+		}
+	}
+	return nil
 }
 
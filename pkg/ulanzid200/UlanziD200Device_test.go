@@ -0,0 +1,428 @@
+package ulanzid200
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+// newTestDevice подключает устройство к FakeTransport без горутин Start,
+// чтобы всё, что оно пишет, можно было проверить синхронно
+func newTestDevice(t *testing.T) (*UlanziD200Device, *FakeConnection) {
+	t.Helper()
+	iconPath := t.TempDir()
+	writeTestIcon(t, filepath.Join(iconPath, "red.png"), color.RGBA{0xff, 0, 0, 0xff})
+	writeTestIcon(t, filepath.Join(iconPath, "blue.png"), color.RGBA{0, 0, 0xff, 0xff})
+
+	transport := NewFakeTransport()
+	d := NewWithTransport(transport, CLOCK, iconPath, t.TempDir())
+	d.SetReconnectDelay(time.Millisecond)
+	if !d.connectToDevice() {
+		t.Fatal("не удалось подключиться к FakeTransport")
+	}
+	t.Cleanup(d.Stop)
+	return d, transport.Connections()[0]
+}
+
+func writeTestIcon(t *testing.T, path string, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// decodeWritten разбирает пакеты, записанные после from
+func decodeWritten(t *testing.T, conn *FakeConnection, from int) []codec.Message {
+	t.Helper()
+	decoder := codec.NewDecoder()
+	var messages []codec.Message
+	for i, packet := range conn.Written()[from:] {
+		if len(packet) != codec.PacketSize {
+			t.Fatalf("пакет %d: размер %d", i, len(packet))
+		}
+		msg, err := decoder.Feed(packet)
+		if err != nil {
+			t.Fatalf("пакет %d: %v", i, err)
+		}
+		if msg != nil {
+			messages = append(messages, msg)
+		}
+	}
+	if decoder.Pending() {
+		t.Fatal("последнее сообщение не дописано")
+	}
+	return messages
+}
+
+// upload — загрузка страницы: команда и содержимое manifest.json
+type upload struct {
+	command  CommandProtocol
+	manifest map[string]manifestButton
+}
+
+type manifestButton struct {
+	State     int                 `json:"State"`
+	ViewParam []map[string]string `json:"ViewParam"`
+}
+
+func parseUpload(t *testing.T, msg codec.Message) upload {
+	t.Helper()
+	var data []byte
+	switch m := msg.(type) {
+	case *codec.SetButtons:
+		data = m.Zip
+	case *codec.PartiallyUpdateButtons:
+		data = m.Zip
+	default:
+		t.Fatalf("ожидалась загрузка страницы, получено %v", msg.Command())
+	}
+	if pos := codec.CheckPayload(data); pos >= 0 {
+		t.Fatalf("запрещённый байт на позиции %d", pos)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range reader.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		files[f.Name] = buf.Bytes()
+	}
+	result := upload{command: msg.Command()}
+	if err := json.Unmarshal(files["manifest.json"], &result.manifest); err != nil {
+		t.Fatal(err)
+	}
+	for key, button := range result.manifest {
+		for _, view := range button.ViewParam {
+			if icon := view["Icon"]; icon != "" && files[icon] == nil {
+				t.Errorf("%s: иконки %s нет в архиве", key, icon)
+			}
+		}
+	}
+	return result
+}
+
+func (u upload) keys() []string {
+	keys := make([]string, 0, len(u.manifest))
+	for key := range u.manifest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSetButtons(t *testing.T) {
+	type call struct {
+		buttons    map[int]Button
+		updateOnly bool
+		// Ожидаемая команда и кнопки в манифесте; пустая команда — ничего не отправлено
+		command CommandProtocol
+		keys    []string
+	}
+	page := map[int]Button{
+		0: {Name: "A", Icon: "red.png"},
+		6: {Name: "B"},
+	}
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{
+			name: "первая загрузка целиком",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+			},
+		},
+		{
+			name: "та же страница не отправляется",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+				{buttons: page},
+			},
+		},
+		{
+			name: "изменённая кнопка загружается отдельно",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+				{
+					buttons: map[int]Button{0: {Name: "A", Icon: "blue.png"}, 6: {Name: "B"}},
+					command: OUT_PARTIALLY_UPDATE_BUTTONS, keys: []string{"0_0"},
+				},
+			},
+		},
+		{
+			name: "пропавшая кнопка очищается",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+				{
+					buttons: map[int]Button{0: {Name: "A", Icon: "red.png"}, 12: {Name: "C"}},
+					command: OUT_PARTIALLY_UPDATE_BUTTONS, keys: []string{"1_1", "2_2"},
+				},
+			},
+		},
+		{
+			name: "updateOnly не очищает остальные кнопки",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+				{
+					buttons: map[int]Button{12: {Name: "C"}}, updateOnly: true,
+					command: OUT_PARTIALLY_UPDATE_BUTTONS, keys: []string{"2_2"},
+				},
+				{buttons: map[int]Button{12: {Name: "C"}}, updateOnly: true},
+			},
+		},
+		{
+			name: "updateOnly до полной загрузки не запоминается",
+			calls: []call{
+				{
+					buttons: map[int]Button{0: {Name: "A"}}, updateOnly: true,
+					command: OUT_PARTIALLY_UPDATE_BUTTONS, keys: []string{"0_0"},
+				},
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+			},
+		},
+		{
+			name: "после переподключения снова целиком",
+			calls: []call{
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+				{buttons: nil},
+				{buttons: page, command: OUT_SET_BUTTONS, keys: []string{"0_0", "1_1"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, conn := newTestDevice(t)
+			for i, c := range tt.calls {
+				if c.buttons == nil {
+					d.resetShown()
+					continue
+				}
+				from := len(conn.Written())
+				d.SetButtons(c.buttons, c.updateOnly)
+				messages := decodeWritten(t, conn, from)
+				if c.command == 0 {
+					if len(messages) != 0 {
+						t.Fatalf("вызов %d: ожидалось без отправки, получено %d сообщений", i+1, len(messages))
+					}
+					continue
+				}
+				if len(messages) != 1 {
+					t.Fatalf("вызов %d: ожидалось одно сообщение, получено %d", i+1, len(messages))
+				}
+				got := parseUpload(t, messages[0])
+				if got.command != c.command {
+					t.Errorf("вызов %d: команда %v, ожидалась %v", i+1, got.command, c.command)
+				}
+				if !reflect.DeepEqual(got.keys(), c.keys) {
+					t.Errorf("вызов %d: кнопки %v, ожидались %v", i+1, got.keys(), c.keys)
+				}
+			}
+		})
+	}
+}
+
+func TestSetButtonsManifest(t *testing.T) {
+	d, conn := newTestDevice(t)
+	d.SetButtons(map[int]Button{
+		3: {Name: "Mute", State: 1, Views: []ButtonView{
+			{Name: "On", Icon: "red.png"},
+			{Name: "Off", Icon: "blue.png"},
+		}},
+		// Иконки, которой нет, в архиве тоже нет
+		4: {Name: "Missing", Icon: "missing.png"},
+	}, false)
+
+	got := parseUpload(t, decodeWritten(t, conn, 0)[0])
+	mute := got.manifest["3_0"]
+	if mute.State != 1 || len(mute.ViewParam) != 2 {
+		t.Fatalf("переключатель: %+v", mute)
+	}
+	if mute.ViewParam[0]["Text"] != "On" || mute.ViewParam[1]["Text"] != "Off" {
+		t.Errorf("подписи состояний: %+v", mute.ViewParam)
+	}
+	if mute.ViewParam[0]["Icon"] == mute.ViewParam[1]["Icon"] {
+		t.Errorf("у состояний одна иконка: %+v", mute.ViewParam)
+	}
+	missing := got.manifest["4_0"]
+	if len(missing.ViewParam) != 1 || missing.ViewParam[0]["Icon"] != "" || missing.ViewParam[0]["Text"] != "Missing" {
+		t.Errorf("кнопка без иконки: %+v", missing)
+	}
+}
+
+func TestSetSmallWindowData(t *testing.T) {
+	data := SmallWindowData{Mode: STATS, CPU: 12, MEM: 34, GPU: 56, Time: "12:34:56"}
+	later := data
+	later.Time = "12:34:57"
+	tests := []struct {
+		name  string
+		calls []SmallWindowData
+		force []bool
+		// Сколько сообщений отправлено после каждого вызова
+		sent []int
+	}{
+		{name: "первые данные", calls: []SmallWindowData{data}, force: []bool{false}, sent: []int{1}},
+		{name: "повтор не отправляется", calls: []SmallWindowData{data, data}, force: []bool{false, false}, sent: []int{1, 0}},
+		{name: "force отправляет повтор", calls: []SmallWindowData{data, data}, force: []bool{false, true}, sent: []int{1, 1}},
+		{name: "изменение отправляется", calls: []SmallWindowData{data, later}, force: []bool{false, false}, sent: []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, conn := newTestDevice(t)
+			for i, call := range tt.calls {
+				from := len(conn.Written())
+				d.SetSmallWindowData(call, tt.force[i])
+				messages := decodeWritten(t, conn, from)
+				if len(messages) != tt.sent[i] {
+					t.Fatalf("вызов %d: отправлено %d сообщений, ожидалось %d", i+1, len(messages), tt.sent[i])
+				}
+				if len(messages) == 0 {
+					continue
+				}
+				// Режим задаёт пульт, а не вызывающий
+				want := codec.SmallWindow{Mode: int(CLOCK), CPU: call.CPU, MEM: call.MEM, Time: call.Time, GPU: call.GPU}
+				if got, ok := messages[0].(*codec.SmallWindow); !ok || *got != want {
+					t.Errorf("вызов %d: %#v, ожидалось %#v", i+1, messages[0], want)
+				}
+			}
+		})
+	}
+}
+
+// waitMessage ждёт сообщение с командой cmd среди записанных в conn
+func waitMessage(t *testing.T, conn *FakeConnection, cmd CommandProtocol) codec.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for n := 1; time.Now().Before(deadline); n++ {
+		conn.WaitWritten(n, 50*time.Millisecond)
+		decoder := codec.NewDecoder()
+		for _, packet := range conn.Written() {
+			if msg, _ := decoder.Feed(packet); msg != nil && msg.Command() == cmd {
+				return msg
+			}
+		}
+	}
+	t.Fatalf("не дождались %v", cmd)
+	return nil
+}
+
+func TestStartReconnect(t *testing.T) {
+	transport := NewFakeTransport()
+	d := NewWithTransport(transport, CLOCK, t.TempDir(), t.TempDir())
+	d.SetReconnectDelay(time.Millisecond)
+	d.Start()
+	defer d.Stop()
+
+	for attempt := 0; attempt < 3; attempt++ {
+		conn := transport.WaitConnection(attempt, 2*time.Second)
+		if conn == nil {
+			t.Fatalf("попытка %d: устройство не подключилось", attempt+1)
+		}
+		conn.InjectDeviceInfo(DeviceInfo{Dversion: "1", SerialNumber: "SN1"})
+		select {
+		case <-d.RefreshChan():
+		case <-time.After(2 * time.Second):
+			t.Fatalf("попытка %d: нет запроса перерисовки после DeviceInfo", attempt+1)
+		}
+		if got := waitMessage(t, conn, OUT_SET_BRIGHTNESS).(*codec.Brightness); got.Value != 100 {
+			t.Errorf("попытка %d: яркость %d", attempt+1, got.Value)
+		}
+		if d.Serial() != "SN1" {
+			t.Errorf("попытка %d: серийный номер %q", attempt+1, d.Serial())
+		}
+
+		conn.InjectButton(2, true)
+		select {
+		case event := <-d.KeyEventChan():
+			if event.Index != 2 || !event.Pressed {
+				t.Errorf("попытка %d: событие %+v", attempt+1, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("попытка %d: нет события кнопки", attempt+1)
+		}
+
+		// Обрыв соединения: устройство без привязки переподключается само
+		conn.Close()
+	}
+}
+
+func TestStartReconnectAfterOpenError(t *testing.T) {
+	transport := NewFakeTransport()
+	transport.OpenError = ErrFakeClosed
+	d := NewWithTransport(transport, CLOCK, t.TempDir(), t.TempDir())
+	d.SetReconnectDelay(time.Millisecond)
+	d.Start()
+	defer d.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	if len(transport.Connections()) != 0 {
+		t.Fatal("подключилось несмотря на ошибку")
+	}
+	transport.mu.Lock()
+	transport.OpenError = nil
+	transport.mu.Unlock()
+	if transport.WaitConnection(0, 2*time.Second) == nil {
+		t.Fatal("устройство не подключилось после исчезновения ошибки")
+	}
+}
+
+func TestBoundDeviceDisconnect(t *testing.T) {
+	transport := NewFakeTransport()
+	info := transport.Enumerate()[0]
+	d := newBoundDevice(transport, info, CLOCK, t.TempDir(), t.TempDir())
+	d.SetReconnectDelay(time.Millisecond)
+	disconnected := make(chan struct{})
+	d.onDisconnect = func(*UlanziD200Device) { close(disconnected) }
+	d.Start()
+	defer d.Stop()
+
+	conn := transport.WaitConnection(0, 2*time.Second)
+	if conn == nil {
+		t.Fatal("устройство не подключилось")
+	}
+	conn.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("нет onDisconnect после обрыва")
+	}
+	select {
+	case <-d.Done():
+	default:
+		t.Error("устройство не остановилось")
+	}
+	// Переподключением привязанного устройства занимается DeviceManager
+	time.Sleep(20 * time.Millisecond)
+	if n := len(transport.Connections()); n != 1 {
+		t.Errorf("соединений: %d", n)
+	}
+}
@@ -0,0 +1,80 @@
+package codec
+
+import "fmt"
+
+// Encode кодирует сообщение в последовательность пакетов по PacketSize байт
+func Encode(msg Message) ([][]byte, error) {
+	payload, err := msg.MarshalPayload()
+	if err != nil {
+		return nil, err
+	}
+	return SplitPayload(msg.Command(), payload), nil
+}
+
+// Decode разбирает одиночный пакет. Если команде нужны пакеты-продолжения,
+// возвращает ErrIncomplete — для потока используйте Decoder.
+func Decode(packet []byte) (Message, error) {
+	header, err := ParseHeader(packet)
+	if err != nil {
+		return nil, err
+	}
+	if int(header.Length)+HeaderSize > len(packet) {
+		if int(header.Length) > FirstChunkSize {
+			return nil, ErrIncomplete
+		}
+		return nil, fmt.Errorf("длина данных превышает размер пакета")
+	}
+	return UnmarshalPayload(header.Command, packet[HeaderSize:HeaderSize+int(header.Length)])
+}
+
+// Decoder собирает сообщения из потока пакетов, склеивая пакеты-продолжения
+type Decoder struct {
+	pending *Header
+	buf     []byte
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Pending сообщает, ожидает ли декодер продолжения сообщения
+func (d *Decoder) Pending() bool {
+	return d.pending != nil
+}
+
+// Reset сбрасывает недособранное сообщение
+func (d *Decoder) Reset() {
+	d.pending = nil
+	d.buf = nil
+}
+
+// Feed принимает очередной пакет. Возвращает сообщение, когда оно собрано
+// целиком, и nil, если нужны ещё пакеты.
+func (d *Decoder) Feed(packet []byte) (Message, error) {
+	if d.pending != nil {
+		remaining := int(d.pending.Length) - len(d.buf)
+		d.buf = append(d.buf, packet[:min(remaining, len(packet))]...)
+		if len(d.buf) < int(d.pending.Length) {
+			return nil, nil
+		}
+		cmd := d.pending.Command
+		data := d.buf
+		d.Reset()
+		return UnmarshalPayload(cmd, data)
+	}
+
+	header, err := ParseHeader(packet)
+	if err != nil {
+		return nil, err
+	}
+	body := packet[HeaderSize:]
+	if int(header.Length) <= len(body) {
+		return UnmarshalPayload(header.Command, body[:header.Length])
+	}
+	if int(header.Length) <= FirstChunkSize {
+		return nil, fmt.Errorf("длина данных превышает размер пакета")
+	}
+	d.pending = &header
+	d.buf = append(make([]byte, 0, header.Length), body...)
+	return nil, nil
+}
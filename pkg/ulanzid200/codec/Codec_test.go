@@ -0,0 +1,286 @@
+package codec
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomPayload возвращает n байт, в которых часто встречаются 0x00 и 0x7c
+func randomPayload(rng *rand.Rand, n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		switch rng.Intn(4) {
+		case 0:
+			data[i] = 0x00
+		case 1:
+			data[i] = 0x7c
+		default:
+			data[i] = byte(rng.Intn(256))
+		}
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	zipData, err := BuildZip([]ZipFile{
+		{Name: "manifest.json", Data: []byte(`{"0_0":{"State":0,"ViewParam":[{"Text":"A"}]}}`)},
+		{Name: "icons/a.png", Data: randomPayload(rng, 5000)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		msg     Message
+		packets int
+	}{
+		{"SetButtons", &SetButtons{Zip: zipData}, 1 + (len(zipData)-FirstChunkSize+PacketSize-1)/PacketSize},
+		{"PartiallyUpdateButtons", &PartiallyUpdateButtons{Zip: zipData[:FirstChunkSize]}, 1},
+		{"SmallWindow", &SmallWindow{Mode: 1, CPU: 12, MEM: 34, Time: "12:34:56", GPU: 7}, 1},
+		{"Brightness", &Brightness{Value: 100}, 1},
+		{"LabelStyle", &LabelStyle{Align: "bottom", Color: 0xffffff, FontName: "Roboto", ShowTitle: true, Size: 10, Weight: 80}, 1},
+		{"ButtonPressed", &ButtonPressed{State: 1, Index: 12, Pressed: true}, 1},
+		{"ButtonReleased", &ButtonPressed{Index: 3}, 1},
+		{"DeviceInfo", &DeviceInfo{Dversion: "1.2.3", SerialNumber: "SN0001"}, 1},
+		{"Unknown", &Unknown{Cmd: 0x0777, Data: []byte{1, 2, 3}}, 1},
+		{"UnknownLong", &Unknown{Cmd: 0x0778, Data: randomPayload(rng, 3000)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets, err := Encode(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(packets) != tt.packets {
+				t.Fatalf("пакетов %d, ожидалось %d", len(packets), tt.packets)
+			}
+			for i, packet := range packets {
+				if len(packet) != PacketSize {
+					t.Fatalf("пакет %d: размер %d", i, len(packet))
+				}
+			}
+
+			decoder := NewDecoder()
+			var got Message
+			for i, packet := range packets {
+				msg, err := decoder.Feed(packet)
+				if err != nil {
+					t.Fatalf("пакет %d: %v", i, err)
+				}
+				if msg != nil && i != len(packets)-1 {
+					t.Fatalf("сообщение собрано на пакете %d из %d", i+1, len(packets))
+				}
+				got = msg
+			}
+			if decoder.Pending() {
+				t.Error("декодер ждёт продолжения")
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("Decoder: %#v, ожидалось %#v", got, tt.msg)
+			}
+
+			got, err = Decode(packets[0])
+			if len(packets) > 1 {
+				if !errors.Is(err, ErrIncomplete) {
+					t.Errorf("Decode: %v, ожидалось ErrIncomplete", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("Decode: %#v, ожидалось %#v", got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	frame := func(cmd CommandProtocol, length int, data []byte) []byte {
+		return BuildFrame(cmd, length, data)
+	}
+	tests := []struct {
+		name   string
+		packet []byte
+		err    error
+	}{
+		{"короткий пакет", []byte{0x7c, 0x7c, 0x01}, ErrShortPacket},
+		{"неверная сигнатура", append([]byte{0x7c, 0x7d}, make([]byte, 10)...), ErrBadHeader},
+		{"нужно продолжение", frame(OUT_SET_BUTTONS, FirstChunkSize+1, nil), ErrIncomplete},
+		{"длина больше пакета", frame(OUT_SET_BRIGHTNESS, 100, []byte("1"))[:50], nil},
+		{"неверная яркость", frame(OUT_SET_BRIGHTNESS, 3, []byte("abc")), nil},
+		{"неверный SmallWindow", frame(OUT_SET_SMALL_WINDOW_DATA, 3, []byte("1|2")), nil},
+		{"неверная кнопка", frame(IN_BUTTON, 4, []byte{0, 1, 0, 1}), nil},
+		{"неверный DeviceInfo", frame(IN_DEVICE_INFO, 2, []byte("{x")), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Decode(tt.packet)
+			if err == nil {
+				t.Fatalf("ожидалась ошибка, получено %#v", msg)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecodeDeviceInfoPadding(t *testing.T) {
+	// Пульт дополняет JSON нулями до конца пакета
+	data := []byte(`{"Dversion":"1","SerialNumber":"SN"}`)
+	packet := BuildFrame(IN_DEVICE_INFO, len(data)+10, data)
+	msg, err := Decode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := msg.(*DeviceInfo); info.SerialNumber != "SN" {
+		t.Errorf("%#v", info)
+	}
+}
+
+func TestEncodeSmallWindowSeparator(t *testing.T) {
+	if _, err := Encode(&SmallWindow{Time: "12|34"}); err == nil {
+		t.Error("время с '|' закодировано")
+	}
+}
+
+func TestSplitPayload(t *testing.T) {
+	for _, n := range []int{0, 1, FirstChunkSize - 1, FirstChunkSize, FirstChunkSize + 1,
+		FirstChunkSize + PacketSize, FirstChunkSize + PacketSize + 1, 10000} {
+		payload := randomPayload(rand.New(rand.NewSource(int64(n))), n)
+		packets := SplitPayload(OUT_SET_BUTTONS, payload)
+
+		want := 1
+		if n > FirstChunkSize {
+			want += (n - FirstChunkSize + PacketSize - 1) / PacketSize
+		}
+		if len(packets) != want {
+			t.Errorf("%d байт: пакетов %d, ожидалось %d", n, len(packets), want)
+			continue
+		}
+		header, err := ParseHeader(packets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Command != OUT_SET_BUTTONS || int(header.Length) != n {
+			t.Errorf("%d байт: заголовок %+v", n, header)
+		}
+
+		var joined []byte
+		joined = append(joined, packets[0][HeaderSize:]...)
+		for i, packet := range packets {
+			if len(packet) != PacketSize {
+				t.Fatalf("%d байт: пакет %d размером %d", n, i, len(packet))
+			}
+			if i > 0 {
+				joined = append(joined, packet...)
+			}
+		}
+		if !bytes.Equal(joined[:n], payload) {
+			t.Errorf("%d байт: данные не совпадают", n)
+		}
+		if bytes.Count(joined[n:], []byte{0}) != len(joined)-n {
+			t.Errorf("%d байт: хвост последнего пакета не заполнен нулями", n)
+		}
+	}
+}
+
+func TestDecoderStream(t *testing.T) {
+	first, _ := Encode(&Unknown{Cmd: 0x0777, Data: bytes.Repeat([]byte{0x7c}, 2500)})
+	second, _ := Encode(&Brightness{Value: 42})
+
+	decoder := NewDecoder()
+	var got []Message
+	for _, packet := range append(first, second...) {
+		msg, err := decoder.Feed(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg != nil {
+			got = append(got, msg)
+		}
+	}
+	if len(got) != 2 || got[0].Command() != 0x0777 || !reflect.DeepEqual(got[1], &Brightness{Value: 42}) {
+		t.Fatalf("%#v", got)
+	}
+
+	// Reset отбрасывает недособранное сообщение, следующий пакет — новый заголовок
+	if msg, err := decoder.Feed(first[0]); msg != nil || err != nil || !decoder.Pending() {
+		t.Fatalf("%v %v %v", msg, err, decoder.Pending())
+	}
+	decoder.Reset()
+	msg, err := decoder.Feed(second[0])
+	if err != nil || !reflect.DeepEqual(msg, &Brightness{Value: 42}) {
+		t.Fatalf("%#v %v", msg, err)
+	}
+}
+
+func TestBuildZipRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 30; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		files := []ZipFile{{Name: "manifest.json", Data: []byte(`{}`)}}
+		for i := 0; i < 1+rng.Intn(4); i++ {
+			files = append(files, ZipFile{
+				Name: "icons/" + string(rune('a'+i)) + ".png",
+				Data: randomPayload(rng, rng.Intn(6000)),
+			})
+		}
+
+		data, err := BuildZip(files)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		packets := SplitPayload(OUT_SET_BUTTONS, data)
+		decoder := NewDecoder()
+		var msg Message
+		for i, packet := range packets {
+			// Прошивка отбрасывает пакет-продолжение с таким первым байтом
+			if i > 0 && bytes.IndexByte(ForbiddenChunkStart, packet[0]) >= 0 {
+				t.Fatalf("seed %d: пакет %d начинается с 0x%02x", seed, i, packet[0])
+			}
+			if msg, err = decoder.Feed(packet); err != nil {
+				t.Fatal(err)
+			}
+		}
+		zipData := msg.(*SetButtons).Zip
+		if !bytes.Equal(zipData, data) {
+			t.Fatalf("seed %d: архив изменился при передаче", seed)
+		}
+
+		reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		got := map[string][]byte{}
+		for _, f := range reader.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[f.Name], err = io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatalf("seed %d: %s: %v", seed, f.Name, err)
+			}
+		}
+		if len(got) != len(files) {
+			t.Fatalf("seed %d: файлов %d, ожидалось %d", seed, len(got), len(files))
+		}
+		for _, file := range files {
+			if !bytes.Equal(got[file.Name], file.Data) {
+				t.Errorf("seed %d: %s не совпадает", seed, file.Name)
+			}
+		}
+	}
+}
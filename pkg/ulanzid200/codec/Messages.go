@@ -0,0 +1,182 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Message — типизированное содержимое команды
+type Message interface {
+	Command() CommandProtocol
+	MarshalPayload() ([]byte, error)
+}
+
+// SetButtons — полная замена страницы кнопок zip-архивом
+type SetButtons struct {
+	Zip []byte
+}
+
+// PartiallyUpdateButtons — обновление части кнопок zip-архивом
+type PartiallyUpdateButtons struct {
+	Zip []byte
+}
+
+// SmallWindow — данные маленького окна: "mode|cpu|mem|time|gpu"
+type SmallWindow struct {
+	Mode int
+	CPU  int
+	MEM  int
+	Time string
+	GPU  int
+}
+
+type Brightness struct {
+	Value int
+}
+
+type LabelStyle struct {
+	Align     string `json:"Align"`
+	Color     int64  `json:"Color"`
+	FontName  string `json:"FontName"`
+	ShowTitle bool   `json:"ShowTitle"`
+	Size      int    `json:"Size"`
+	Weight    int    `json:"Weight"`
+}
+
+// ButtonPressed — данные кнопки из 4 байтов: state, index, const(0x01), pressed
+type ButtonPressed struct {
+	State   byte
+	Index   byte
+	Pressed bool
+}
+
+type DeviceInfo struct {
+	Dversion     string `json:"Dversion"`
+	SerialNumber string `json:"SerialNumber"`
+	Error        string `json:"error"`
+}
+
+// Unknown — команда, которую кодек не знает; данные сохраняются как есть
+type Unknown struct {
+	Cmd  CommandProtocol
+	Data []byte
+}
+
+func (*SetButtons) Command() CommandProtocol             { return OUT_SET_BUTTONS }
+func (*PartiallyUpdateButtons) Command() CommandProtocol { return OUT_PARTIALLY_UPDATE_BUTTONS }
+func (*SmallWindow) Command() CommandProtocol            { return OUT_SET_SMALL_WINDOW_DATA }
+func (*Brightness) Command() CommandProtocol             { return OUT_SET_BRIGHTNESS }
+func (*LabelStyle) Command() CommandProtocol             { return OUT_SET_LABEL_STYLE }
+func (*ButtonPressed) Command() CommandProtocol          { return IN_BUTTON }
+func (*DeviceInfo) Command() CommandProtocol             { return IN_DEVICE_INFO }
+func (m *Unknown) Command() CommandProtocol              { return m.Cmd }
+
+func (m *SetButtons) MarshalPayload() ([]byte, error) {
+	return m.Zip, nil
+}
+
+func (m *PartiallyUpdateButtons) MarshalPayload() ([]byte, error) {
+	return m.Zip, nil
+}
+
+func (m *SmallWindow) MarshalPayload() ([]byte, error) {
+	if strings.Contains(m.Time, "|") {
+		return nil, fmt.Errorf("недопустимый символ '|' во времени: %q", m.Time)
+	}
+	return []byte(fmt.Sprintf("%d|%v|%v|%v|%v", m.Mode, m.CPU, m.MEM, m.Time, m.GPU)), nil
+}
+
+func (m *Brightness) MarshalPayload() ([]byte, error) {
+	return []byte(strconv.Itoa(m.Value)), nil
+}
+
+func (m *LabelStyle) MarshalPayload() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *ButtonPressed) MarshalPayload() ([]byte, error) {
+	data := []byte{m.State, m.Index, 0x01, 0x00}
+	if m.Pressed {
+		data[3] = 0x01
+	}
+	return data, nil
+}
+
+func (m *DeviceInfo) MarshalPayload() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *Unknown) MarshalPayload() ([]byte, error) {
+	return m.Data, nil
+}
+
+// UnmarshalPayload разбирает полезную нагрузку команды в типизированную структуру.
+// Для неизвестных команд возвращает *Unknown.
+func UnmarshalPayload(cmd CommandProtocol, data []byte) (Message, error) {
+	switch cmd {
+	case OUT_SET_BUTTONS:
+		return &SetButtons{Zip: append([]byte(nil), data...)}, nil
+
+	case OUT_PARTIALLY_UPDATE_BUTTONS:
+		return &PartiallyUpdateButtons{Zip: append([]byte(nil), data...)}, nil
+
+	case OUT_SET_SMALL_WINDOW_DATA:
+		parts := strings.Split(string(data), "|")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("неверный формат SmallWindow: %q", data)
+		}
+		var msg SmallWindow
+		var err error
+		if msg.Mode, err = strconv.Atoi(parts[0]); err != nil {
+			return nil, fmt.Errorf("неверный режим SmallWindow: %w", err)
+		}
+		if msg.CPU, err = strconv.Atoi(parts[1]); err != nil {
+			return nil, fmt.Errorf("неверное значение CPU: %w", err)
+		}
+		if msg.MEM, err = strconv.Atoi(parts[2]); err != nil {
+			return nil, fmt.Errorf("неверное значение MEM: %w", err)
+		}
+		msg.Time = parts[3]
+		if msg.GPU, err = strconv.Atoi(parts[4]); err != nil {
+			return nil, fmt.Errorf("неверное значение GPU: %w", err)
+		}
+		return &msg, nil
+
+	case OUT_SET_BRIGHTNESS:
+		value, err := strconv.Atoi(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("неверное значение яркости: %w", err)
+		}
+		return &Brightness{Value: value}, nil
+
+	case OUT_SET_LABEL_STYLE:
+		var msg LabelStyle
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("неверный формат LabelStyle: %w", err)
+		}
+		return &msg, nil
+
+	case IN_BUTTON:
+		if len(data) < 4 || data[2] != 0x01 {
+			return nil, fmt.Errorf("неверный формат ButtonPressedStruct")
+		}
+		return &ButtonPressed{
+			State:   data[0],
+			Index:   data[1],
+			Pressed: data[3] == 0x01,
+		}, nil
+
+	case IN_DEVICE_INFO:
+		var msg DeviceInfo
+		text := bytes.Trim(data, "\x00")
+		if err := json.Unmarshal(text, &msg); err != nil {
+			return nil, fmt.Errorf("неверный формат DeviceInfo: %w", err)
+		}
+		return &msg, nil
+	}
+
+	return &Unknown{Cmd: cmd, Data: append([]byte(nil), data...)}, nil
+}
@@ -0,0 +1,98 @@
+// Package codec реализует кодирование и разбор пакетов протокола Ulanzi D200
+// в обе стороны: исходящих (контроллер -> пульт) и входящих (пульт -> контроллер).
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type CommandProtocol uint16
+
+const (
+	OUT_SET_BUTTONS              CommandProtocol = 0x0001
+	OUT_PARTIALLY_UPDATE_BUTTONS CommandProtocol = 0x000d
+	OUT_SET_SMALL_WINDOW_DATA    CommandProtocol = 0x0006
+	OUT_SET_BRIGHTNESS           CommandProtocol = 0x000a
+	OUT_SET_LABEL_STYLE          CommandProtocol = 0x000b
+	IN_BUTTON                    CommandProtocol = 0x0101
+	IN_DEVICE_INFO               CommandProtocol = 0x0303
+)
+
+const (
+	PacketSize = 1024
+	HeaderSize = 8
+	// Сколько байт полезной нагрузки помещается в первый пакет
+	FirstChunkSize = PacketSize - HeaderSize
+)
+
+var (
+	ErrShortPacket = errors.New("входной пакет слишком короткий")
+	ErrBadHeader   = errors.New("неверная сигнатура")
+	ErrIncomplete  = errors.New("пакет требует продолжения")
+)
+
+func (c CommandProtocol) String() string {
+	switch c {
+	case OUT_SET_BUTTONS:
+		return "OUT_SET_BUTTONS"
+	case OUT_PARTIALLY_UPDATE_BUTTONS:
+		return "OUT_PARTIALLY_UPDATE_BUTTONS"
+	case OUT_SET_SMALL_WINDOW_DATA:
+		return "OUT_SET_SMALL_WINDOW_DATA"
+	case OUT_SET_BRIGHTNESS:
+		return "OUT_SET_BRIGHTNESS"
+	case OUT_SET_LABEL_STYLE:
+		return "OUT_SET_LABEL_STYLE"
+	case IN_BUTTON:
+		return "IN_BUTTON"
+	case IN_DEVICE_INFO:
+		return "IN_DEVICE_INFO"
+	}
+	return fmt.Sprintf("0x%04x", uint16(c))
+}
+
+// Header — заголовок первого пакета команды
+type Header struct {
+	Command CommandProtocol
+	Length  uint32
+}
+
+// ParseHeader разбирает 8-байтный заголовок: 0x7c7c, команда (BE), длина (LE)
+func ParseHeader(packet []byte) (Header, error) {
+	if len(packet) < HeaderSize {
+		return Header{}, ErrShortPacket
+	}
+	if packet[0] != 0x7c || packet[1] != 0x7c {
+		return Header{}, ErrBadHeader
+	}
+	return Header{
+		Command: CommandProtocol(binary.BigEndian.Uint16(packet[2:4])),
+		Length:  binary.LittleEndian.Uint32(packet[4:8]),
+	}, nil
+}
+
+// BuildFrame собирает первый пакет команды размером PacketSize.
+// length — полная длина полезной нагрузки, data — её часть, влезающая в пакет.
+func BuildFrame(cmd CommandProtocol, length int, data []byte) []byte {
+	frame := make([]byte, PacketSize)
+	frame[0], frame[1] = 0x7c, 0x7c
+	binary.BigEndian.PutUint16(frame[2:4], uint16(cmd))
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(length))
+	copy(frame[HeaderSize:], data)
+	return frame
+}
+
+// SplitPayload разбивает полезную нагрузку на пакеты: первый с заголовком,
+// остальные — сырые куски по PacketSize байт, дополненные нулями.
+func SplitPayload(cmd CommandProtocol, payload []byte) [][]byte {
+	first := payload[:min(FirstChunkSize, len(payload))]
+	packets := [][]byte{BuildFrame(cmd, len(payload), first)}
+	for i := FirstChunkSize; i < len(payload); i += PacketSize {
+		chunk := make([]byte, PacketSize)
+		copy(chunk, payload[i:min(i+PacketSize, len(payload))])
+		packets = append(packets, chunk)
+	}
+	return packets
+}
@@ -0,0 +1,307 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+)
+
+// Байты, с которых прошивка не принимает пакет-продолжение. В полезной
+// нагрузке это позиции FirstChunkSize + n*PacketSize.
+var ForbiddenChunkStart = []byte{0x00, 0x7c}
+
+// IsChunkStart — с позиции offset полезной нагрузки начинается пакет-продолжение
+func IsChunkStart(offset int) bool {
+	return offset >= FirstChunkSize && (offset-FirstChunkSize)%PacketSize == 0
+}
+
+func isForbidden(b byte) bool {
+	return bytes.IndexByte(ForbiddenChunkStart, b) >= 0
+}
+
+// CheckPayload возвращает позицию первого запрещённого байта в начале
+// пакета-продолжения или -1, если нагрузку можно отправлять
+func CheckPayload(payload []byte) int {
+	for i := FirstChunkSize; i < len(payload); i += PacketSize {
+		if isForbidden(payload[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ZipFile — файл страницы кнопок
+type ZipFile struct {
+	Name string
+	Data []byte
+}
+
+// ID дополнительного поля, которым архив выравнивается под запрещённые байты
+const ZipPaddingID = 0xD935
+
+// Байт-заполнитель дополнительного поля
+const zipPaddingByte = 0xff
+
+var ErrZipLayout = errors.New("не удалось разместить архив без запрещённых байт")
+
+// zip-даты: 1980-01-01 00:00, чтобы одинаковые страницы давали одинаковые архивы
+const (
+	zipTime = 0
+	zipDate = 1<<5 | 1
+)
+
+type zipEntry struct {
+	name     string
+	method   uint16
+	crc      uint32
+	size     uint32
+	data     []byte
+	offset   uint32
+	external uint32
+}
+
+// BuildZip собирает zip-архив в памяти так, чтобы в начале каждого
+// пакета-продолжения не оказалось запрещённого байта. В каждую запись при
+// необходимости добавляется дополнительное поле ZipPaddingID нужной длины,
+// которое сдвигает всё, что идёт после него, поэтому архив получается
+// с первой попытки и одинаковым для одинаковых файлов. Каталоги создаются
+// автоматически.
+func BuildZip(files []ZipFile) ([]byte, error) {
+	entries, err := zipEntries(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("пустой архив")
+	}
+
+	var out []byte
+	for i := range entries {
+		entry := &entries[i]
+		entry.offset = uint32(len(out))
+		// За последней локальной записью идёт первая запись каталога
+		next := func(int) []segment { return centralPrefix(&entries[0]) }
+		if i+1 < len(entries) {
+			next = func(int) []segment { return localPrefix(&entries[i+1]) }
+		}
+		extra, err := placeRecord(len(out), func(extra []byte) []segment {
+			return []segment{{data: localHeader(entry, extra)}, {data: entry.data}}
+		}, next)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, entry.name)
+		}
+		out = append(out, localHeader(entry, extra)...)
+		out = append(out, entry.data...)
+	}
+
+	cdOffset := len(out)
+	for i := range entries {
+		entry := &entries[i]
+		// Конец каталога содержит его размер, поэтому проверяется целиком
+		next := func(extraLen int) []segment {
+			cdSize := len(out) + 46 + len(entry.name) + extraLen - cdOffset
+			return []segment{{data: endOfCentralDirectory(len(entries), cdSize, cdOffset)}}
+		}
+		if i+1 < len(entries) {
+			next = func(int) []segment { return centralPrefix(&entries[i+1]) }
+		}
+		extra, err := placeRecord(len(out), func(extra []byte) []segment {
+			return []segment{{data: centralHeader(entry, extra)}}
+		}, next)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, entry.name)
+		}
+		out = append(out, centralHeader(entry, extra)...)
+	}
+	out = append(out, endOfCentralDirectory(len(entries), len(out)-cdOffset, cdOffset)...)
+	if pos := CheckPayload(out); pos >= 0 {
+		return nil, fmt.Errorf("%w: позиция %d", ErrZipLayout, pos)
+	}
+	return out, nil
+}
+
+// segment — кусок архива при проверке раскладки; any байт могут быть любыми
+type segment struct {
+	data []byte
+	any  int
+}
+
+func (s segment) len() int {
+	return len(s.data) + s.any
+}
+
+// placeRecord подбирает дополнительное поле записи, начинающейся с позиции
+// start, так чтобы ни сама запись, ни заголовок следующей записи не попали
+// запрещёнными байтами на начало пакета
+func placeRecord(start int, record func(extra []byte) []segment, next func(extraLen int) []segment) ([]byte, error) {
+	// Поле короче 4 байт невозможно; длины до PacketSize+4 дают все сдвиги,
+	// в том числе с ненулевым старшим байтом длины
+	for padding := 0; padding <= PacketSize+4; padding++ {
+		if padding > 0 && padding < 4 {
+			continue
+		}
+		extra := paddingExtra(padding)
+		if segmentsValid(start, append(record(extra), next(len(extra))...)) {
+			return extra, nil
+		}
+	}
+	return nil, ErrZipLayout
+}
+
+func segmentsValid(start int, segments []segment) bool {
+	pos := start
+	for _, seg := range segments {
+		end := pos + seg.len()
+		for i := nextChunkStart(pos); i < end; i += PacketSize {
+			if offset := i - pos; offset < len(seg.data) && isForbidden(seg.data[offset]) {
+				return false
+			}
+		}
+		pos = end
+	}
+	return true
+}
+
+// nextChunkStart возвращает первую позицию начала пакета-продолжения не раньше pos
+func nextChunkStart(pos int) int {
+	if pos <= FirstChunkSize {
+		return FirstChunkSize
+	}
+	return FirstChunkSize + (pos-FirstChunkSize+PacketSize-1)/PacketSize*PacketSize
+}
+
+// localPrefix и centralPrefix — заголовок следующей записи до её
+// дополнительного поля. Длина поля ещё не выбрана, её байты проверит
+// placeRecord самой записи.
+func localPrefix(entry *zipEntry) []segment {
+	header := localHeader(entry, nil)
+	return []segment{{data: header[:28]}, {any: 2}, {data: header[30:]}}
+}
+
+func centralPrefix(entry *zipEntry) []segment {
+	header := centralHeader(entry, nil)
+	return []segment{{data: header[:30]}, {any: 2}, {data: header[32:]}}
+}
+
+func paddingExtra(length int) []byte {
+	if length == 0 {
+		return nil
+	}
+	extra := make([]byte, length)
+	binary.LittleEndian.PutUint16(extra[0:], ZipPaddingID)
+	binary.LittleEndian.PutUint16(extra[2:], uint16(length-4))
+	for i := 4; i < length; i++ {
+		extra[i] = zipPaddingByte
+	}
+	return extra
+}
+
+// zipEntries сжимает файлы и добавляет записи для их каталогов
+func zipEntries(files []ZipFile) ([]zipEntry, error) {
+	var entries []zipEntry
+	dirs := map[string]bool{}
+	for _, file := range files {
+		name := strings.TrimPrefix(file.Name, "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			return nil, fmt.Errorf("неверное имя файла в архиве: %q", file.Name)
+		}
+		for i := strings.Index(name, "/"); i >= 0; i = nextSlash(name, i) {
+			dir := name[:i+1]
+			if !dirs[dir] {
+				dirs[dir] = true
+				// drwxr-xr-x в старших битах внешних атрибутов, как у archive/zip
+				entries = append(entries, zipEntry{name: dir, external: (0o40755 << 16) | 0x10})
+			}
+		}
+
+		entry := zipEntry{
+			name:     name,
+			crc:      crc32.ChecksumIEEE(file.Data),
+			size:     uint32(len(file.Data)),
+			data:     file.Data,
+			external: 0o100644 << 16,
+		}
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(file.Data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if buf.Len() < len(file.Data) {
+			entry.method = 8
+			entry.data = buf.Bytes()
+		}
+		entries = append(entries, entry)
+	}
+	// Каталоги перед файлами в них, остальное по имени
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+func nextSlash(name string, prev int) int {
+	i := strings.Index(name[prev+1:], "/")
+	if i < 0 {
+		return -1
+	}
+	return prev + 1 + i
+}
+
+func localHeader(entry *zipEntry, extra []byte) []byte {
+	b := make([]byte, 30, 30+len(entry.name)+len(extra))
+	binary.LittleEndian.PutUint32(b[0:], 0x04034b50)
+	binary.LittleEndian.PutUint16(b[4:], 20)
+	binary.LittleEndian.PutUint16(b[6:], 0)
+	binary.LittleEndian.PutUint16(b[8:], entry.method)
+	binary.LittleEndian.PutUint16(b[10:], zipTime)
+	binary.LittleEndian.PutUint16(b[12:], zipDate)
+	binary.LittleEndian.PutUint32(b[14:], entry.crc)
+	binary.LittleEndian.PutUint32(b[18:], uint32(len(entry.data)))
+	binary.LittleEndian.PutUint32(b[22:], entry.size)
+	binary.LittleEndian.PutUint16(b[26:], uint16(len(entry.name)))
+	binary.LittleEndian.PutUint16(b[28:], uint16(len(extra)))
+	b = append(b, entry.name...)
+	return append(b, extra...)
+}
+
+func centralHeader(entry *zipEntry, extra []byte) []byte {
+	b := make([]byte, 46, 46+len(entry.name)+len(extra))
+	binary.LittleEndian.PutUint32(b[0:], 0x02014b50)
+	// Версия 2.0, атрибуты Unix
+	binary.LittleEndian.PutUint16(b[4:], 3<<8|20)
+	binary.LittleEndian.PutUint16(b[6:], 20)
+	binary.LittleEndian.PutUint16(b[8:], 0)
+	binary.LittleEndian.PutUint16(b[10:], entry.method)
+	binary.LittleEndian.PutUint16(b[12:], zipTime)
+	binary.LittleEndian.PutUint16(b[14:], zipDate)
+	binary.LittleEndian.PutUint32(b[16:], entry.crc)
+	binary.LittleEndian.PutUint32(b[20:], uint32(len(entry.data)))
+	binary.LittleEndian.PutUint32(b[24:], entry.size)
+	binary.LittleEndian.PutUint16(b[28:], uint16(len(entry.name)))
+	binary.LittleEndian.PutUint16(b[30:], uint16(len(extra)))
+	binary.LittleEndian.PutUint32(b[38:], entry.external)
+	binary.LittleEndian.PutUint32(b[42:], entry.offset)
+	b = append(b, entry.name...)
+	return append(b, extra...)
+}
+
+func endOfCentralDirectory(count, cdSize, cdOffset int) []byte {
+	b := make([]byte, 22)
+	binary.LittleEndian.PutUint32(b[0:], 0x06054b50)
+	binary.LittleEndian.PutUint16(b[8:], uint16(count))
+	binary.LittleEndian.PutUint16(b[10:], uint16(count))
+	binary.LittleEndian.PutUint32(b[12:], uint32(cdSize))
+	binary.LittleEndian.PutUint32(b[16:], uint32(cdOffset))
+	return b
+}
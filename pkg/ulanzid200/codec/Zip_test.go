@@ -0,0 +1,210 @@
+package codec
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestIsChunkStart(t *testing.T) {
+	tests := []struct {
+		offset int
+		want   bool
+	}{
+		{0, false},
+		{FirstChunkSize - 1, false},
+		{FirstChunkSize, true},
+		{FirstChunkSize + 1, false},
+		{FirstChunkSize + PacketSize - 1, false},
+		{FirstChunkSize + PacketSize, true},
+		{FirstChunkSize + PacketSize + 1, false},
+		{FirstChunkSize + 5*PacketSize, true},
+	}
+	for _, tt := range tests {
+		if got := IsChunkStart(tt.offset); got != tt.want {
+			t.Errorf("IsChunkStart(%d) = %v", tt.offset, got)
+		}
+	}
+}
+
+func TestNextChunkStart(t *testing.T) {
+	tests := []struct{ pos, want int }{
+		{0, FirstChunkSize},
+		{FirstChunkSize - 1, FirstChunkSize},
+		{FirstChunkSize, FirstChunkSize},
+		{FirstChunkSize + 1, FirstChunkSize + PacketSize},
+		{FirstChunkSize + PacketSize, FirstChunkSize + PacketSize},
+		{FirstChunkSize + PacketSize + 1, FirstChunkSize + 2*PacketSize},
+	}
+	for _, tt := range tests {
+		if got := nextChunkStart(tt.pos); got != tt.want {
+			t.Errorf("nextChunkStart(%d) = %d, ожидалось %d", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPayload(t *testing.T) {
+	// set кладёт байты value на позиции at в нагрузку длины n из единиц
+	set := func(n int, value byte, at ...int) []byte {
+		payload := bytes.Repeat([]byte{0x01}, n)
+		for _, i := range at {
+			payload[i] = value
+		}
+		return payload
+	}
+	tests := []struct {
+		name    string
+		payload []byte
+		want    int
+	}{
+		{"короткая нагрузка", set(FirstChunkSize, 0x00, 0, FirstChunkSize-1), -1},
+		{"0x00 перед границей", set(FirstChunkSize+1, 0x00, FirstChunkSize-1), -1},
+		{"0x00 на границе", set(FirstChunkSize+1, 0x00, FirstChunkSize), FirstChunkSize},
+		{"0x7c на границе", set(FirstChunkSize+1, 0x7c, FirstChunkSize), FirstChunkSize},
+		{"0x00 после границы", set(FirstChunkSize+2, 0x00, FirstChunkSize+1), -1},
+		{"0x7c на второй границе", set(FirstChunkSize+PacketSize+1, 0x7c, FirstChunkSize+PacketSize), FirstChunkSize + PacketSize},
+		{"0x00 перед второй границей", set(FirstChunkSize+PacketSize+1, 0x00, FirstChunkSize+PacketSize-1), -1},
+		{"первая из двух", set(FirstChunkSize+PacketSize+1, 0x00, FirstChunkSize, FirstChunkSize+PacketSize), FirstChunkSize},
+		{"разрешённый байт на границе", set(FirstChunkSize+1, 0x7d, FirstChunkSize), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPayload(tt.payload); got != tt.want {
+				t.Errorf("CheckPayload = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaddingExtra(t *testing.T) {
+	tests := []struct {
+		length int
+		size   uint16
+	}{
+		{4, 0},
+		{5, 1},
+		// Старший байт длины ненулевой
+		{PacketSize + 4, PacketSize},
+	}
+	if extra := paddingExtra(0); extra != nil {
+		t.Errorf("paddingExtra(0) = %v", extra)
+	}
+	for _, tt := range tests {
+		extra := paddingExtra(tt.length)
+		if len(extra) != tt.length {
+			t.Fatalf("paddingExtra(%d): длина %d", tt.length, len(extra))
+		}
+		if id := binary.LittleEndian.Uint16(extra); id != ZipPaddingID {
+			t.Errorf("paddingExtra(%d): ID 0x%04x", tt.length, id)
+		}
+		if size := binary.LittleEndian.Uint16(extra[2:]); size != tt.size {
+			t.Errorf("paddingExtra(%d): размер %d, ожидался %d", tt.length, size, tt.size)
+		}
+		if bytes.Count(extra[4:], []byte{zipPaddingByte}) != tt.length-4 {
+			t.Errorf("paddingExtra(%d): заполнитель %v", tt.length, extra[4:])
+		}
+	}
+}
+
+func TestPlaceRecordImpossible(t *testing.T) {
+	// Запрещённые байты на всех позициях: никакой сдвиг не поможет
+	zeros := make([]byte, 2*PacketSize)
+	_, err := placeRecord(0, func(extra []byte) []segment {
+		return []segment{{data: extra}, {data: zeros}}
+	}, func(int) []segment { return nil })
+	if !errors.Is(err, ErrZipLayout) {
+		t.Errorf("ошибка %v, ожидалась ErrZipLayout", err)
+	}
+}
+
+func TestPlaceRecordShiftsOffBoundary(t *testing.T) {
+	// Без сдвига 0x00 попадает ровно на первую границу
+	data := bytes.Repeat([]byte{0x01}, FirstChunkSize+10)
+	data[FirstChunkSize] = 0x00
+	extra, err := placeRecord(0, func(extra []byte) []segment {
+		return []segment{{data: extra}, {data: data}}
+	}, func(int) []segment { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extra) < 4 {
+		t.Fatalf("длина поля %d", len(extra))
+	}
+	if CheckPayload(append(extra, data...)) >= 0 {
+		t.Error("запрещённый байт остался на границе")
+	}
+}
+
+// paddedRecords считает записи архива с полем ZipPaddingID
+func paddedRecords(t *testing.T, data []byte) int {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded := 0
+	for _, f := range reader.File {
+		for extra := f.Extra; len(extra) >= 4; {
+			size := int(binary.LittleEndian.Uint16(extra[2:]))
+			if binary.LittleEndian.Uint16(extra) == ZipPaddingID {
+				padded++
+			}
+			if 4+size > len(extra) {
+				break
+			}
+			extra = extra[4+size:]
+		}
+	}
+	return padded
+}
+
+func TestBuildZipBoundarySweep(t *testing.T) {
+	// Размер файла и длина имени сдвигают каждый заголовок и данные через
+	// все позиции вокруг первых двух границ пакетов
+	rng := rand.New(rand.NewSource(19))
+	padded := 0
+	for size := FirstChunkSize - 120; size <= FirstChunkSize+PacketSize+120; size += 7 {
+		for nameLen := 1; nameLen <= 8; nameLen++ {
+			files := []ZipFile{
+				{Name: "manifest.json", Data: []byte(`{"0_0":{"State":0}}`)},
+				{Name: "icons/" + strings.Repeat("a", nameLen) + ".png", Data: randomPayload(rng, size)},
+			}
+			data, err := BuildZip(files)
+			if err != nil {
+				t.Fatalf("размер %d, имя %d: %v", size, nameLen, err)
+			}
+			if pos := CheckPayload(data); pos >= 0 {
+				t.Fatalf("размер %d, имя %d: запрещённый байт на позиции %d", size, nameLen, pos)
+			}
+			again, err := BuildZip(files)
+			if err != nil || !bytes.Equal(again, data) {
+				t.Fatalf("размер %d, имя %d: архив не детерминирован", size, nameLen)
+			}
+			padded += paddedRecords(t, data)
+
+			reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("размер %d, имя %d: %v", size, nameLen, err)
+			}
+			for _, f := range reader.File {
+				r, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = io.Copy(io.Discard, r)
+				r.Close()
+				if err != nil {
+					t.Fatalf("размер %d, имя %d: %s: %v", size, nameLen, f.Name, err)
+				}
+			}
+		}
+	}
+	if padded == 0 {
+		t.Error("ни одной записи с выравнивающим полем: границы не проверены")
+	}
+}
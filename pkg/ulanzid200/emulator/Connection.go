@@ -0,0 +1,70 @@
+package emulator
+
+import (
+	"sync"
+)
+
+// connection — открытое соединение с эмулятором со стороны контроллера
+type connection struct {
+	emulator  *Emulator
+	incoming  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnection(e *Emulator) *connection {
+	return &connection{
+		emulator: e,
+		incoming: make(chan []byte, 256),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (c *connection) Read(b []byte) (int, error) {
+	select {
+	case packet := <-c.incoming:
+		return copy(b, packet), nil
+	case <-c.closed:
+		return 0, ErrClosed
+	}
+}
+
+func (c *connection) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, ErrClosed
+	default:
+	}
+	// Ошибки протокола копятся в эмуляторе, для контроллера запись успешна,
+	// как и у настоящего пульта
+	c.emulator.handlePacket(b)
+	return len(b), nil
+}
+
+func (c *connection) Close() error {
+	c.emulator.mu.Lock()
+	defer c.emulator.mu.Unlock()
+	if c.emulator.conn == c {
+		c.emulator.conn = nil
+	}
+	c.close()
+	return nil
+}
+
+func (c *connection) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// push кладёт пакет в очередь чтения контроллера
+func (c *connection) push(packet []byte) bool {
+	select {
+	case <-c.closed:
+		return false
+	case c.incoming <- packet:
+		return true
+	default:
+		return false
+	}
+}
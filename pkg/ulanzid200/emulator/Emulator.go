@@ -0,0 +1,268 @@
+// Package emulator — программный эмулятор Ulanzi D200. Принимает тот же поток
+// байт, что пишет контроллер, и ведёт модель состояния пульта. Реализует
+// ulanzid200.Transport, поэтому подключается вместо реального HID.
+package emulator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+var (
+	ErrClosed    = errors.New("emulator: connection closed")
+	ErrUnplugged = errors.New("emulator: device unplugged")
+)
+
+type Emulator struct {
+	mu      sync.Mutex
+	info    ulanzid200.HIDInfo
+	version string
+	plugged bool
+	conn    *connection
+	decoder *codec.Decoder
+	state   State
+	errors  []error
+	changed chan struct{}
+	hotplug *ulanzid200.ChanHotplugSource
+}
+
+func New(serial string) *Emulator {
+	return &Emulator{
+		info: ulanzid200.HIDInfo{
+			Path:         "emulator:" + serial,
+			VendorID:     ulanzid200.VendorID,
+			ProductID:    ulanzid200.ProductID,
+			Serial:       serial,
+			Manufacturer: "dispeys",
+			Product:      "D200 emulator",
+		},
+		version: "emulator",
+		plugged: true,
+		decoder: codec.NewDecoder(),
+		changed: make(chan struct{}, 1),
+		hotplug: ulanzid200.NewChanHotplugSource(),
+	}
+}
+
+// Hotplug возвращает источник событий Plug/Unplug для DeviceManager
+func (e *Emulator) Hotplug() ulanzid200.HotplugSource {
+	return e.hotplug
+}
+
+func (e *Emulator) hotplugEvent(eventType ulanzid200.HotplugEventType) *ulanzid200.HotplugEvent {
+	return &ulanzid200.HotplugEvent{
+		Type:      eventType,
+		DevPath:   e.info.Path,
+		VendorID:  e.info.VendorID,
+		ProductID: e.info.ProductID,
+	}
+}
+
+func (e *Emulator) Serial() string {
+	return e.info.Serial
+}
+
+func (e *Emulator) Supported() bool {
+	return true
+}
+
+func (e *Emulator) Enumerate() []ulanzid200.HIDInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.plugged {
+		return nil
+	}
+	return []ulanzid200.HIDInfo{e.info}
+}
+
+func (e *Emulator) Open(info ulanzid200.HIDInfo) (ulanzid200.Connection, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.plugged || info.Path != e.info.Path {
+		return nil, ErrUnplugged
+	}
+	if e.conn != nil {
+		e.conn.close()
+	}
+	e.decoder.Reset()
+	e.conn = newConnection(e)
+	// Как и настоящий пульт, сразу после подключения сообщаем о себе
+	e.sendLocked(&codec.DeviceInfo{Dversion: e.version, SerialNumber: e.info.Serial})
+	return e.conn, nil
+}
+
+// Unplug эмулирует отключение пульта
+func (e *Emulator) Unplug() {
+	e.mu.Lock()
+	e.plugged = false
+	if e.conn != nil {
+		e.conn.close()
+		e.conn = nil
+	}
+	e.mu.Unlock()
+	e.hotplug.Emit(e.hotplugEvent(ulanzid200.Disconnected))
+}
+
+// Plug эмулирует подключение пульта. Состояние экрана при этом сбрасывается.
+func (e *Emulator) Plug() {
+	e.mu.Lock()
+	e.plugged = true
+	e.state = State{}
+	e.notifyLocked()
+	e.mu.Unlock()
+	e.hotplug.Emit(e.hotplugEvent(ulanzid200.Connected))
+}
+
+// State возвращает копию текущего состояния пульта
+func (e *Emulator) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.clone()
+}
+
+// Errors возвращает накопленные нарушения протокола
+func (e *Emulator) Errors() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]error(nil), e.errors...)
+}
+
+// Changed сигнализирует об изменении состояния
+func (e *Emulator) Changed() <-chan struct{} {
+	return e.changed
+}
+
+// Press генерирует нажатие кнопки
+func (e *Emulator) Press(index int) error {
+	return e.sendButton(index, true)
+}
+
+// Release генерирует отпускание кнопки
+func (e *Emulator) Release(index int) error {
+	return e.sendButton(index, false)
+}
+
+// Click генерирует нажатие и отпускание кнопки
+func (e *Emulator) Click(index int) error {
+	if err := e.Press(index); err != nil {
+		return err
+	}
+	return e.Release(index)
+}
+
+// SendDeviceInfo повторно отправляет информацию об устройстве
+func (e *Emulator) SendDeviceInfo() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sendLocked(&codec.DeviceInfo{Dversion: e.version, SerialNumber: e.info.Serial})
+}
+
+func (e *Emulator) sendButton(index int, pressed bool) error {
+	if index < 0 || index > ulanzid200.ButtonCount {
+		return fmt.Errorf("неверный индекс кнопки: %d", index)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := byte(0)
+	if btn := e.buttonLocked(index); btn != nil {
+		state = byte(btn.State)
+	}
+	return e.sendLocked(&codec.ButtonPressed{State: state, Index: byte(index), Pressed: pressed})
+}
+
+func (e *Emulator) buttonLocked(index int) *Button {
+	if index < 0 || index >= ulanzid200.ButtonCount {
+		return nil
+	}
+	return e.state.Buttons[index]
+}
+
+func (e *Emulator) sendLocked(msg codec.Message) error {
+	if e.conn == nil {
+		return ErrClosed
+	}
+	packets, err := codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	for _, packet := range packets {
+		if !e.conn.push(packet) {
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// handlePacket обрабатывает пакет, записанный контроллером
+func (e *Emulator) handlePacket(packet []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(packet) != codec.PacketSize {
+		return e.failLocked(fmt.Errorf("неверный размер пакета: %d", len(packet)))
+	}
+	if e.decoder.Pending() {
+		for _, b := range codec.ForbiddenChunkStart {
+			if packet[0] == b {
+				e.failLocked(fmt.Errorf("запрещённый байт 0x%02x в начале пакета-продолжения", b))
+			}
+		}
+	}
+
+	msg, err := e.decoder.Feed(packet)
+	if err != nil {
+		e.decoder.Reset()
+		return e.failLocked(err)
+	}
+	if msg == nil {
+		return nil
+	}
+
+	switch m := msg.(type) {
+	case *codec.SetButtons:
+		buttons, err := parsePage(m.Zip)
+		if err != nil {
+			return e.failLocked(err)
+		}
+		e.state.Buttons = [ulanzid200.ButtonCount]*Button{}
+		for index, btn := range buttons {
+			e.state.Buttons[index] = btn
+		}
+		e.state.FullUploads++
+	case *codec.PartiallyUpdateButtons:
+		buttons, err := parsePage(m.Zip)
+		if err != nil {
+			return e.failLocked(err)
+		}
+		for index, btn := range buttons {
+			e.state.Buttons[index] = btn
+		}
+		e.state.PartialUploads++
+	case *codec.Brightness:
+		e.state.Brightness = m.Value
+	case *codec.LabelStyle:
+		e.state.LabelStyle = *m
+	case *codec.SmallWindow:
+		e.state.SmallWindow = *m
+	default:
+		return e.failLocked(fmt.Errorf("неожиданная команда %v", msg.Command()))
+	}
+	e.notifyLocked()
+	return nil
+}
+
+func (e *Emulator) failLocked(err error) error {
+	e.errors = append(e.errors, err)
+	return err
+}
+
+func (e *Emulator) notifyLocked() {
+	select {
+	case e.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+package emulator
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+)
+
+type manifestEntry struct {
+	State     int                 `json:"State"`
+	ViewParam []map[string]string `json:"ViewParam"`
+}
+
+// parsePage распаковывает загруженную страницу и возвращает кнопки по индексам
+func parsePage(data []byte) (map[int]*Button, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть архив: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	var manifestFile *zip.File
+	for _, f := range reader.File {
+		files[f.Name] = f
+		if path.Base(f.Name) == "manifest.json" && (manifestFile == nil || len(f.Name) < len(manifestFile.Name)) {
+			manifestFile = f
+		}
+	}
+	if manifestFile == nil {
+		return nil, fmt.Errorf("в архиве нет manifest.json")
+	}
+	root := path.Dir(manifestFile.Name)
+
+	manifestData, err := readZipFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var manifest map[string]manifestEntry
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга manifest.json: %w", err)
+	}
+
+	buttons := make(map[int]*Button)
+	for key, entry := range manifest {
+		var col, row int
+		if _, err := fmt.Sscanf(key, "%d_%d", &col, &row); err != nil {
+			return nil, fmt.Errorf("неверный ключ кнопки %q", key)
+		}
+		if col < 0 || col >= ulanzid200.ButtonCols || row < 0 || row >= ulanzid200.ButtonRows {
+			return nil, fmt.Errorf("кнопка %q вне сетки", key)
+		}
+		index := row*ulanzid200.ButtonCols + col
+		if index >= ulanzid200.ButtonCount {
+			return nil, fmt.Errorf("кнопка %q вне сетки", key)
+		}
+
+		btn := &Button{State: entry.State}
+		for _, param := range entry.ViewParam {
+			view := View{Text: param["Text"], Icon: param["Icon"]}
+			if view.Icon != "" {
+				name := path.Join(root, view.Icon)
+				f, ok := files[name]
+				if !ok {
+					return nil, fmt.Errorf("иконка %s не найдена в архиве", view.Icon)
+				}
+				if view.IconData, err = readZipFile(f); err != nil {
+					return nil, err
+				}
+			}
+			btn.Views = append(btn.Views, view)
+		}
+		buttons[index] = btn
+	}
+	return buttons, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %w", f.Name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать %s: %w", f.Name, err)
+	}
+	return data, nil
+}
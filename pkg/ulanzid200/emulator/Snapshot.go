@@ -0,0 +1,254 @@
+package emulator
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+const (
+	SnapshotMargin = 24
+	SnapshotGap    = 20
+
+	SnapshotWidth  = ulanzid200.ButtonCols*ulanzid200.IconWidth + (ulanzid200.ButtonCols-1)*SnapshotGap + 2*SnapshotMargin
+	SnapshotHeight = ulanzid200.ButtonRows*ulanzid200.IconHeight + (ulanzid200.ButtonRows-1)*SnapshotGap + 2*SnapshotMargin
+
+	// Размер шрифта в LabelStyle задан для экрана пульта, иконки на нём вдвое меньше
+	labelScale = 2
+)
+
+// Режимы маленького окна, как их передаёт контроллер
+const (
+	smallWindowStats      = int(ulanzid200.STATS)
+	smallWindowClock      = int(ulanzid200.CLOCK)
+	smallWindowBackground = int(ulanzid200.BACKGROUND)
+)
+
+var (
+	backgroundColor  = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	cellColor        = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	smallWindowColor = color.RGBA{0x10, 0x10, 0x18, 0xff}
+	smallWindowText  = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// CellRect возвращает прямоугольник кнопки на снимке
+func CellRect(index int) image.Rectangle {
+	row := index / ulanzid200.ButtonCols
+	col := index % ulanzid200.ButtonCols
+	x := SnapshotMargin + col*(ulanzid200.IconWidth+SnapshotGap)
+	y := SnapshotMargin + row*(ulanzid200.IconHeight+SnapshotGap)
+	return image.Rect(x, y, x+ulanzid200.IconWidth, y+ulanzid200.IconHeight)
+}
+
+// SmallWindowRect — маленькое окно занимает две последние ячейки нижнего ряда
+func SmallWindowRect() image.Rectangle {
+	return CellRect(ulanzid200.ButtonCount).Union(CellRect(ulanzid200.ButtonCount + 1))
+}
+
+// Render рисует состояние пульта: сетку 3x5 с иконками и подписями
+// и маленькое окно
+func Render(state State) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, SnapshotWidth, SnapshotHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+
+	for index := 0; index < ulanzid200.ButtonCount; index++ {
+		renderButton(img, CellRect(index), state.Buttons[index], state.LabelStyle)
+	}
+	renderSmallWindow(img, SmallWindowRect(), state.SmallWindow.Mode, state)
+	return img
+}
+
+func renderButton(img *image.RGBA, rect image.Rectangle, btn *Button, style codec.LabelStyle) {
+	draw.Draw(img, rect, image.NewUniform(cellColor), image.Point{}, draw.Src)
+
+	view := btn.CurrentView()
+	if view == nil {
+		return
+	}
+	if len(view.IconData) > 0 {
+		icon, _, err := image.Decode(bytes.NewReader(view.IconData))
+		if err != nil {
+			drawText(img, rect, "?", "middle", 48, 700, color.RGBA{0xff, 0x00, 0x00, 0xff})
+		} else {
+			xdraw.CatmullRom.Scale(img, fitRect(icon.Bounds(), rect), icon, icon.Bounds(), draw.Over, nil)
+		}
+	}
+	if view.Text != "" && style.ShowTitle {
+		drawText(img, rect, view.Text, style.Align, float64(style.Size*labelScale), style.Weight, intColor(style.Color))
+	}
+}
+
+func renderSmallWindow(img *image.RGBA, rect image.Rectangle, mode int, state State) {
+	draw.Draw(img, rect, image.NewUniform(smallWindowColor), image.Point{}, draw.Src)
+	data := state.SmallWindow
+	switch mode {
+	case smallWindowStats:
+		lines := []string{
+			fmt.Sprintf("CPU %d%%", data.CPU),
+			fmt.Sprintf("MEM %d%%", data.MEM),
+			fmt.Sprintf("GPU %d%%", data.GPU),
+		}
+		lineHeight := rect.Dy() / len(lines)
+		for i, line := range lines {
+			lineRect := image.Rect(rect.Min.X, rect.Min.Y+i*lineHeight, rect.Max.X, rect.Min.Y+(i+1)*lineHeight)
+			drawText(img, lineRect, line, "middle", 36, 400, smallWindowText)
+		}
+	case smallWindowClock:
+		drawText(img, rect, data.Time, "middle", 72, 700, smallWindowText)
+	case smallWindowBackground:
+	}
+}
+
+// fitRect вписывает src в dst с сохранением пропорций и центрированием
+func fitRect(src, dst image.Rectangle) image.Rectangle {
+	if src.Dx() == 0 || src.Dy() == 0 {
+		return dst
+	}
+	w, h := dst.Dx(), dst.Dy()
+	if src.Dx()*dst.Dy() > src.Dy()*dst.Dx() {
+		h = src.Dy() * dst.Dx() / src.Dx()
+	} else {
+		w = src.Dx() * dst.Dy() / src.Dy()
+	}
+	x := dst.Min.X + (dst.Dx()-w)/2
+	y := dst.Min.Y + (dst.Dy()-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
+func intColor(value int64) color.RGBA {
+	return color.RGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 0xff}
+}
+
+var (
+	fontsOnce   sync.Once
+	regularFont *opentype.Font
+	boldFont    *opentype.Font
+	facesMu     sync.Mutex
+	facesCache  = map[string]font.Face{}
+)
+
+func getFace(size float64, weight int) font.Face {
+	fontsOnce.Do(func() {
+		regularFont, _ = opentype.Parse(goregular.TTF)
+		boldFont, _ = opentype.Parse(gobold.TTF)
+	})
+	if size <= 0 {
+		size = 20
+	}
+	f := regularFont
+	if weight >= 600 {
+		f = boldFont
+	}
+	key := fmt.Sprintf("%v/%v", size, f == boldFont)
+
+	facesMu.Lock()
+	defer facesMu.Unlock()
+	if face, ok := facesCache[key]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	facesCache[key] = face
+	return face
+}
+
+// drawText рисует строку по центру прямоугольника с выравниванием
+// по вертикали: top, middle (center) или bottom
+func drawText(img *image.RGBA, rect image.Rectangle, text, align string, size float64, weight int, c color.Color) {
+	face := getFace(size, weight)
+	if face == nil {
+		return
+	}
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+	metrics := face.Metrics()
+	width := drawer.MeasureString(text).Ceil()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	padding := rect.Dy() / 20
+
+	x := rect.Min.X + (rect.Dx()-width)/2
+	var y int
+	switch align {
+	case "top":
+		y = rect.Min.Y + padding + ascent
+	case "middle", "center":
+		y = rect.Min.Y + (rect.Dy()+ascent-descent)/2
+	default:
+		y = rect.Max.Y - padding - descent
+	}
+	drawer.Dot = fixed.P(x, y)
+	drawer.DrawString(text)
+}
+
+// Snapshot рисует текущее состояние эмулятора
+func (e *Emulator) Snapshot() *image.RGBA {
+	return Render(e.State())
+}
+
+func (e *Emulator) WritePNG(w io.Writer) error {
+	return png.Encode(w, e.Snapshot())
+}
+
+// SavePNG сохраняет снимок экрана пульта в файл
+func (e *Emulator) SavePNG(path string) error {
+	var buf bytes.Buffer
+	if err := e.WritePNG(&buf); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write temp file error: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file error: %w", err)
+	}
+	return nil
+}
+
+// DiffImages считает пиксели, отличающиеся больше чем на tolerance
+// по любому из каналов. Изображения разного размера отличаются целиком.
+func DiffImages(a, b image.Image, tolerance uint8) int {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return max(a.Bounds().Dx()*a.Bounds().Dy(), b.Bounds().Dx()*b.Bounds().Dy())
+	}
+	diff := 0
+	offset := b.Bounds().Min.Sub(a.Bounds().Min)
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			ca := color.RGBAModel.Convert(a.At(x, y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.At(x+offset.X, y+offset.Y)).(color.RGBA)
+			if channelDiff(ca.R, cb.R) > tolerance || channelDiff(ca.G, cb.G) > tolerance ||
+				channelDiff(ca.B, cb.B) > tolerance || channelDiff(ca.A, cb.A) > tolerance {
+				diff++
+			}
+		}
+	}
+	return diff
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,142 @@
+package emulator
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталонные снимки в testdata")
+
+// Сглаживание шрифтов может немного отличаться между версиями x/image
+const (
+	goldenTolerance = 16
+	goldenMaxDiff   = 200
+)
+
+// testIcon — PNG с прямоугольником цвета c на прозрачном фоне
+func testIcon(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := h / 8; y < h-h/8; y++ {
+		for x := w / 8; x < w-w/8; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRenderGolden(t *testing.T) {
+	red := testIcon(t, ulanzid200.IconWidth, ulanzid200.IconHeight, color.RGBA{0xe0, 0x30, 0x30, 0xff})
+	blue := testIcon(t, 64, 32, color.RGBA{0x30, 0x60, 0xe0, 0xff})
+	style := codec.LabelStyle{Align: "bottom", Color: 0xffffff, ShowTitle: true, Size: 10, Weight: 80}
+
+	tests := []struct {
+		name  string
+		state State
+	}{
+		{"empty", State{}},
+		{"buttons", State{
+			Buttons: [ulanzid200.ButtonCount]*Button{
+				0: {Views: []View{{Text: "Copy", IconData: red}}},
+				1: {Views: []View{{IconData: blue}}},
+				// Переключатель во втором состоянии
+				2: {State: 1, Views: []View{{Text: "Off", IconData: red}, {Text: "On", IconData: blue}}},
+				5: {Views: []View{{Text: "Label"}}},
+				// Испорченная иконка рисуется знаком вопроса
+				12: {Views: []View{{IconData: []byte("not a png")}}},
+			},
+			LabelStyle:  style,
+			SmallWindow: codec.SmallWindow{Mode: smallWindowClock, Time: "12:34:56"},
+		}},
+		{"stats", State{
+			Buttons: [ulanzid200.ButtonCount]*Button{
+				0: {Views: []View{{Text: "Top", IconData: blue}}},
+				6: {Views: []View{{Text: "Bold", IconData: red}}},
+			},
+			LabelStyle:  codec.LabelStyle{Align: "top", Color: 0xffd000, ShowTitle: true, Size: 12, Weight: 700},
+			SmallWindow: codec.SmallWindow{Mode: smallWindowStats, CPU: 12, MEM: 34, GPU: 56},
+		}},
+		{"hidden-titles", State{
+			Buttons: [ulanzid200.ButtonCount]*Button{
+				0: {Views: []View{{Text: "Hidden", IconData: red}}},
+			},
+			LabelStyle:  codec.LabelStyle{Align: "bottom", Color: 0xffffff, ShowTitle: false, Size: 10},
+			SmallWindow: codec.SmallWindow{Mode: smallWindowBackground},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.state)
+			path := filepath.Join("testdata", tt.name+".png")
+			if *update {
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, got); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("%v (эталон создаётся с -update)", err)
+			}
+			defer f.Close()
+			want, err := png.Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := DiffImages(got, want, goldenTolerance); diff > goldenMaxDiff {
+				actual := filepath.Join(t.TempDir(), tt.name+".png")
+				var buf bytes.Buffer
+				png.Encode(&buf, got)
+				os.WriteFile(actual, buf.Bytes(), 0o644)
+				t.Errorf("снимок отличается от %s в %d пикселях, получено: %s", path, diff, actual)
+			}
+		})
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	changed := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	changed.Set(1, 1, color.RGBA{10, 0, 0, 0})
+	changed.Set(2, 2, color.RGBA{0, 0, 0, 200})
+	// То же изображение со сдвинутыми границами
+	shifted := image.NewRGBA(image.Rect(5, 5, 9, 9))
+	shifted.Set(6, 6, color.RGBA{10, 0, 0, 0})
+
+	tests := []struct {
+		name      string
+		a, b      image.Image
+		tolerance uint8
+		want      int
+	}{
+		{"одинаковые", base, base, 0, 0},
+		{"два пикселя", base, changed, 0, 2},
+		{"в пределах допуска", base, changed, 10, 1},
+		{"сдвинутые границы", base, shifted, 0, 1},
+		{"разный размер", base, image.NewRGBA(image.Rect(0, 0, 2, 3)), 255, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffImages(tt.a, tt.b, tt.tolerance); got != tt.want {
+				t.Errorf("DiffImages = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package emulator
+
+import (
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200"
+	"github.com/bjaka-max/dispeys/pkg/ulanzid200/codec"
+)
+
+// View — одно из представлений кнопки (ViewParam в manifest.json)
+type View struct {
+	Text     string
+	Icon     string
+	IconData []byte
+}
+
+type Button struct {
+	State int
+	Views []View
+}
+
+// CurrentView возвращает представление, соответствующее состоянию кнопки
+func (b *Button) CurrentView() *View {
+	if b == nil || len(b.Views) == 0 {
+		return nil
+	}
+	if b.State >= 0 && b.State < len(b.Views) {
+		return &b.Views[b.State]
+	}
+	return &b.Views[0]
+}
+
+// State — модель того, что сейчас показывает пульт
+type State struct {
+	Buttons     [ulanzid200.ButtonCount]*Button
+	LabelStyle  codec.LabelStyle
+	Brightness  int
+	SmallWindow codec.SmallWindow
+	// Сколько раз загружалась страница целиком и частично
+	FullUploads    int
+	PartialUploads int
+}
+
+func (s *State) clone() State {
+	result := *s
+	for i, btn := range s.Buttons {
+		if btn == nil {
+			continue
+		}
+		copied := *btn
+		copied.Views = append([]View(nil), btn.Views...)
+		result.Buttons[i] = &copied
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package xdg
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func homeDir() string {
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// DataHome — $XDG_DATA_HOME, по умолчанию ~/.local/share
+func DataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(homeDir(), ".local", "share")
+}
+
+// ConfigHome — $XDG_CONFIG_HOME, по умолчанию ~/.config
+func ConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(homeDir(), ".config")
+}
+
+// DataDirs возвращает DataHome и каталоги $XDG_DATA_DIRS в порядке приоритета
+func DataDirs() []string {
+	dirs := []string{DataHome()}
+	env := os.Getenv("XDG_DATA_DIRS")
+	if env == "" {
+		env = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range strings.Split(env, ":") {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// ApplicationDirs — каталоги с .desktop-файлами
+func ApplicationDirs() []string {
+	var dirs []string
+	for _, dir := range DataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "applications"))
+	}
+	return dirs
+}
+
+// FindDesktopFile ищет .desktop-файл приложения по его id, например
+// org.gnome.Nautilus или firefox.desktop. Возвращает пустую строку, если не нашёлся.
+func FindDesktopFile(id string) string {
+	if !strings.HasSuffix(id, ".desktop") {
+		id += ".desktop"
+	}
+	for _, dir := range ApplicationDirs() {
+		path := filepath.Join(dir, id)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// DesktopEntry — ключи группы [Desktop Entry] .desktop-файла
+type DesktopEntry map[string]string
+
+// ReadDesktopEntry читает группу [Desktop Entry]; локализованные ключи вида Name[ru] сохраняются как есть
+func ReadDesktopEntry(path string) (DesktopEntry, error) {
+	return ReadIniGroup(path, "Desktop Entry")
+}
+
+// ReadIniGroup читает одну группу из ini-подобного файла (index.theme, settings.ini)
+func ReadIniGroup(path, group string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseGroup(bufio.NewScanner(f), group), nil
+}
+
+// ReadIni читает все группы ini-подобного файла
+func ReadIni(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	groups := make(map[string]map[string]string)
+	var current map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+			if groups[name] == nil {
+				groups[name] = make(map[string]string)
+			}
+			current = groups[name]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return groups, scanner.Err()
+}
+
+func parseGroup(scanner *bufio.Scanner, group string) map[string]string {
+	result := make(map[string]string)
+	inGroup := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if inGroup {
+				break
+			}
+			inGroup = line[1:len(line)-1] == group
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return result
+}